@@ -11,12 +11,30 @@ import (
 
 func main() {
 	publish := flag.Bool("publish", false, "run publish phase (default: false)")
+	plan := flag.Bool("plan", false, "show what would be done, without doing it")
+	format := flag.String("format", "text", "plan output format: text or json")
 	flag.Parse()
 
+	if flag.Arg(0) == "schema" {
+		if err := goshipdone.Schema(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	if *publish {
 		os.Setenv("SKIP_PUBLISH", "false")
 	}
 
+	if *plan {
+		if err := goshipdone.Plan("", os.Stdout, *format); err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
 	if err := goshipdone.Run(""); err != nil {
 		log.Fatalln(err)
 	}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/julian7/goshipdone"
+	"github.com/julian7/goshipdone/ctx"
+	intmod "github.com/julian7/goshipdone/internal/modules"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+	"gopkg.in/yaml.v3"
+)
+
+const initTemplate = `---
+setups:
+- type: project
+  name: %s
+builds:
+- type: go
+  goos:
+  - linux
+  - windows
+  goarch:
+  - amd64
+- type: tar
+  compression: gzip
+- type: checksum
+  builds:
+  - archive
+publishes:
+- type: show
+`
+
+func (cli *cli) cmdRun(args []string) int {
+	var (
+		config      configFlags
+		only        stringList
+		skipModules stringList
+	)
+
+	flags := cli.newFlagSet("run", &config)
+	publish := flags.Bool("publish", false, "run publish stage")
+	flags.Var(&only, "only", "run only the specified stage (repeatable)")
+	flags.Var(&skipModules, "skip-module", "skip modules of a kind, in `[stage:]type` format (repeatable)")
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	pipe, err := config.load()
+	if err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	pipe.Publish = *publish
+
+	if len(only) > 0 {
+		if err := pipe.Only(only...); err != nil {
+			return cli.fail(exitUsage, err)
+		}
+	}
+
+	if err := pipe.RemoveModules(skipModules...); err != nil {
+		return cli.fail(exitUsage, err)
+	}
+
+	cx, stop := goshipdone.SignalContext()
+	defer stop()
+
+	if err := pipe.Run(cx); err != nil {
+		return cli.fail(exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdPublish(args []string) int {
+	var config configFlags
+
+	flags := cli.newFlagSet("publish", &config)
+	manifest := flags.String("manifest", "", "manifest of the earlier run (default: metadata.json in the target directory)")
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	pipe, err := config.load()
+	if err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	if err := pipe.StartAt("publish"); err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	cx, stop := goshipdone.SignalContext()
+	defer stop()
+
+	if err := pipe.RunFromManifest(cx, *manifest); err != nil {
+		return cli.fail(exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdCheck(args []string) int {
+	var config configFlags
+
+	flags := cli.newFlagSet("check", &config)
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if _, err := config.load(); err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	fmt.Fprintln(cli.stdout, "configuration is valid")
+
+	return exitOK
+}
+
+func (cli *cli) cmdPlan(args []string) int {
+	var config configFlags
+
+	flags := cli.newFlagSet("plan", &config)
+	format := flags.String("format", "text", "output format: text or json")
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	pipe, err := config.load()
+	if err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	plan, err := pipe.Plan()
+	if err != nil {
+		return cli.fail(exitFailure, err)
+	}
+
+	if err := plan.Write(cli.stdout, *format); err != nil {
+		return cli.fail(exitUsage, err)
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdSchema(args []string) int {
+	flags := cli.newFlagSet("schema", nil)
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if err := goshipdone.Schema(cli.stdout); err != nil {
+		return cli.fail(exitFailure, err)
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdModules(args []string) int {
+	flags := cli.newFlagSet("modules", nil)
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	intmod.Register()
+
+	for _, registration := range modules.Registrations() {
+		mod := registration.Factory()
+
+		fmt.Fprintln(cli.stdout, registration.Kind())
+
+		if describer, ok := mod.(modules.Describer); ok {
+			fmt.Fprintf(cli.stdout, "  %s\n", describer.Describe().Summary)
+		}
+
+		defaults, err := yaml.Marshal(mod)
+		if err != nil {
+			return cli.fail(exitFailure, fmt.Errorf("encoding defaults of %s: %w", registration.Kind(), err))
+		}
+
+		if content := strings.TrimSpace(string(defaults)); content != "{}" {
+			for _, line := range strings.Split(content, "\n") {
+				fmt.Fprintf(cli.stdout, "    %s\n", line)
+			}
+		}
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdInit(args []string) int {
+	var config configFlags
+
+	flags := cli.newFlagSet("init", &config)
+	force := flags.Bool("force", false, "overwrite existing configuration file")
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	filename := config.filename
+	if filename == "" {
+		filename = ".goshipdone.yml"
+	}
+
+	if _, err := os.Stat(filename); err == nil && !*force {
+		return cli.fail(exitFailure, fmt.Errorf("%s already exists, use -force to overwrite", filename))
+	}
+
+	name := "project"
+	if pwd, err := os.Getwd(); err == nil {
+		name = path.Base(pwd)
+	}
+
+	if err := os.WriteFile(filename, []byte(fmt.Sprintf(initTemplate, name)), 0o644); err != nil { // nolint: gosec
+		return cli.fail(exitFailure, err)
+	}
+
+	fmt.Fprintf(cli.stdout, "%s created\n", filename)
+
+	return exitOK
+}
+
+func (cli *cli) cmdArtifacts(args []string) int {
+	var config configFlags
+
+	flags := cli.newFlagSet("artifacts", &config)
+	asJSON := flags.Bool("json", false, "write artifacts in JSON format")
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	pipe, err := config.load()
+	if err != nil {
+		return cli.fail(exitConfig, err)
+	}
+
+	manifest, err := ctx.LoadManifest(path.Join(targetDir(pipe), ctx.ManifestFilename))
+	if err != nil {
+		return cli.fail(exitFailure, err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(cli.stdout)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(manifest.Artifacts); err != nil {
+			return cli.fail(exitFailure, err)
+		}
+
+		return exitOK
+	}
+
+	for _, art := range manifest.Artifacts {
+		fmt.Fprintf(cli.stdout, "%s: %s (%s) %s\n", art.ID, art.Filename, art.OsArch.String(), art.Location)
+	}
+
+	return exitOK
+}
+
+func (cli *cli) cmdVersion(args []string) int {
+	flags := cli.newFlagSet("version", nil)
+
+	if code, ok := cli.parseFlags(flags, args); !ok {
+		return code
+	}
+
+	fmt.Fprintf(cli.stdout, "goshipdone %s\n", version)
+
+	return exitOK
+}
+
+// targetDir returns the target directory of the project module in the
+// setup stage
+func targetDir(pipe *pipeline.Pipeline) string {
+	dir := ""
+
+	if setup := pipe.StageByName("setup"); setup != nil {
+		for _, mod := range setup.Modules {
+			if project, ok := mod.Pluggable.(*intmod.Project); ok {
+				dir = project.TargetDir
+			}
+		}
+	}
+
+	return dir
+}
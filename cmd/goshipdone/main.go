@@ -0,0 +1,172 @@
+// goshipdone command runs GoShipDone pipelines, and helps maintaining their
+// configuration.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+// Exit codes
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitConfig
+)
+
+type (
+	command struct {
+		summary string
+		run     func(cli *cli, args []string) int
+	}
+
+	// cli is an invocation of the command, with its outputs
+	cli struct {
+		stdout io.Writer
+		stderr io.Writer
+	}
+
+	// stringList is a flag.Value collecting all values of a repeated flag
+	stringList []string
+
+	// configFlags are flags of commands loading configuration
+	configFlags struct {
+		filename string
+		profiles stringList
+	}
+)
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+
+	return nil
+}
+
+// nolint: gochecknoglobals
+var (
+	commands map[string]*command
+	version  = "dev"
+)
+
+// nolint: gochecknoinits
+func init() {
+	commands = map[string]*command{
+		"artifacts": {"list artifacts of the last run", (*cli).cmdArtifacts},
+		"check":     {"validate configuration", (*cli).cmdCheck},
+		"init":      {"create a configuration file", (*cli).cmdInit},
+		"modules":   {"list registered modules with their defaults", (*cli).cmdModules},
+		"plan":      {"show what run would do, without doing it", (*cli).cmdPlan},
+		"publish":   {"publish artifacts of an earlier run", (*cli).cmdPublish},
+		"run":       {"run the pipeline", (*cli).cmdRun},
+		"schema":    {"write JSON Schema of the configuration", (*cli).cmdSchema},
+		"version":   {"show version information", (*cli).cmdVersion},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run dispatches a subcommand, and returns its exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	cli := &cli{stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		usage(stderr)
+
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %s\n\n", args[0])
+		usage(stderr)
+
+		return exitUsage
+	}
+
+	return cmd.run(cli, args[1:])
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: goshipdone <command> [options]\n\nCommands:\n")
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintf(w, "\nRun `goshipdone <command> -h` for command options.\n")
+}
+
+// newFlagSet returns a flag set for a command, with configuration flags
+// if the command loads configuration
+func (cli *cli) newFlagSet(name string, config *configFlags) *flag.FlagSet {
+	flags := flag.NewFlagSet("goshipdone "+name, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+
+	if config != nil {
+		flags.StringVar(&config.filename, "config", "", "configuration file (default: autodetect)")
+		flags.Var(&config.profiles, "profile", "merge a profile on top of the configuration (repeatable)")
+	}
+
+	return flags
+}
+
+// load loads the pipeline with the selected configuration flags
+func (config *configFlags) load() (*pipeline.Pipeline, error) {
+	return goshipdone.Load(config.filename, config.profiles...)
+}
+
+// parseFlags parses command line arguments, and returns an exit code if
+// the command shouldn't continue.
+func (cli *cli) parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+
+		return exitUsage, false
+	}
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(cli.stderr, "%s: unexpected arguments: %s\n", flags.Name(), strings.Join(flags.Args(), " "))
+
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
+
+// fail reports an error, and returns its exit code
+func (cli *cli) fail(code int, err error) int {
+	fmt.Fprintf(cli.stderr, "goshipdone: %v\n", err)
+
+	return code
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const testConfig = `---
+setups:
+- type: project
+  name: hello
+  target: %s
+builds:
+- type: show
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	valid := path.Join(dir, "valid.yml")
+	invalid := path.Join(dir, "invalid.yml")
+	unbuilt := path.Join(dir, "unbuilt.yml")
+
+	if err := os.WriteFile(valid, []byte(strings.Replace(testConfig, "%s", path.Join(dir, "dist"), 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(unbuilt, []byte(strings.Replace(testConfig, "%s", path.Join(dir, "unbuilt"), 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(path.Join(dir, "dist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(
+		path.Join(dir, "dist", "metadata.json"),
+		[]byte(`{"artifacts": [{"id": "default", "filename": "hello", "location": "dist/hello", "checksum": ""}]}`),
+		0o600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(invalid, []byte("---\nbuilds:\n- type: go\n  goarhc: [amd64]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		stdout   string
+		stderr   string
+	}{
+		{
+			name:     "no arguments",
+			wantCode: exitUsage,
+			stderr:   "Usage: goshipdone <command>",
+		},
+		{
+			name:     "help",
+			args:     []string{"help"},
+			wantCode: exitOK,
+			stdout:   "Usage: goshipdone <command>",
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"deploy"},
+			wantCode: exitUsage,
+			stderr:   "unknown command: deploy",
+		},
+		{
+			name:     "bad flag",
+			args:     []string{"check", "-bogus"},
+			wantCode: exitUsage,
+			stderr:   "flag provided but not defined: -bogus",
+		},
+		{
+			name:     "unexpected arguments",
+			args:     []string{"check", "-config", valid, "extra"},
+			wantCode: exitUsage,
+			stderr:   "goshipdone check: unexpected arguments: extra",
+		},
+		{
+			name:     "command help",
+			args:     []string{"plan", "-h"},
+			wantCode: exitOK,
+			stderr:   "-format",
+		},
+		{
+			name:     "missing config",
+			args:     []string{"check", "-config", path.Join(dir, "missing.yml")},
+			wantCode: exitConfig,
+			stderr:   "loading GoShipDone file",
+		},
+		{
+			name:     "config error",
+			args:     []string{"check", "-config", invalid},
+			wantCode: exitConfig,
+			stderr:   `invalid.yml: line 4, column 3: module build:go: unknown field "goarhc"`,
+		},
+		{
+			name:     "unknown profile",
+			args:     []string{"check", "-config", valid, "-profile", "nightly"},
+			wantCode: exitConfig,
+			stderr:   `unknown profile "nightly"`,
+		},
+		{
+			name:     "check",
+			args:     []string{"check", "-config", valid},
+			wantCode: exitOK,
+			stdout:   "configuration is valid",
+		},
+		{
+			name:     "plan",
+			args:     []string{"plan", "-config", valid},
+			wantCode: exitOK,
+			stdout:   "build:\n  - show\npublish: skipped\n",
+		},
+		{
+			name:     "plan in json",
+			args:     []string{"plan", "-config", valid, "-format", "json"},
+			wantCode: exitOK,
+			stdout:   `"type": "show"`,
+		},
+		{
+			name:     "plan in unknown format",
+			args:     []string{"plan", "-config", valid, "-format", "xml"},
+			wantCode: exitUsage,
+			stderr:   "xml",
+		},
+		{
+			name:     "artifacts",
+			args:     []string{"artifacts", "-config", valid},
+			wantCode: exitOK,
+			stdout:   "default: hello (noarch) dist/hello",
+		},
+		{
+			name:     "artifacts without a run",
+			args:     []string{"artifacts", "-config", unbuilt},
+			wantCode: exitFailure,
+			stderr:   "metadata.json",
+		},
+		{
+			name:     "init over existing file",
+			args:     []string{"init", "-config", valid},
+			wantCode: exitFailure,
+			stderr:   "valid.yml already exists, use -force to overwrite",
+		},
+		{
+			name:     "schema",
+			args:     []string{"schema"},
+			wantCode: exitOK,
+			stdout:   `"$schema": "http://json-schema.org/draft-07/schema#"`,
+		},
+		{
+			name:     "version",
+			args:     []string{"version"},
+			wantCode: exitOK,
+			stdout:   "goshipdone dev",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d\nstdout: %s\nstderr: %s", code, tt.wantCode, stdout.String(), stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("run() stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("run() stderr = %q, want %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
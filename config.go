@@ -0,0 +1,612 @@
+package goshipdone
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	intmod "github.com/julian7/goshipdone/internal/modules"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	includeKey  = "include"
+	profilesKey = "profiles"
+	mergeKey    = "id"
+	typeKey     = "type"
+	// fileIndent is the indentation of file contents in the composed
+	// document
+	fileIndent = 2
+)
+
+// nolint: gochecknoglobals
+var (
+	syntaxErrorRE = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+	schemeRE      = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+)
+
+type (
+	// composer builds a single configuration from multiple files. Files
+	// are concatenated into a single YAML document, each file under its
+	// own key, to allow anchors to be shared between files. Then, file
+	// contents are deep-merged in order.
+	composer struct {
+		fs       afero.Fs
+		loaded   map[string]bool
+		sources  []*source
+		document bytes.Buffer
+		lines    int
+	}
+
+	// source is a file in the composed document
+	source struct {
+		filename string
+		key      string
+		// start is the line of the file's first line in the composed
+		// document
+		start int
+		lines int
+	}
+
+	// includeList is a list of files to be included, in either a single
+	// string, or a list of strings format.
+	includeList []string
+)
+
+func (list *includeList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*list = includeList{node.Value}
+
+		return nil
+	}
+
+	var items []string
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+
+	*list = items
+
+	return nil
+}
+
+// newComposer returns a composer reading files from a file system
+func newComposer(fs afero.Fs) *composer {
+	return &composer{fs: fs, loaded: map[string]bool{}}
+}
+
+// compose reads configuration files, and returns a single YAML node,
+// with all includes, overlays, and the selected profiles merged. Later
+// files override earlier ones.
+func (comp *composer) compose(filenames []string, profiles []string) (*yaml.Node, error) {
+	// modules are matched by their default IDs while merging
+	intmod.Register()
+
+	for _, filename := range filenames {
+		if err := comp.add(filename, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	node, err := comp.merge()
+	if err != nil {
+		return nil, comp.translate(err)
+	}
+
+	node, err = applyProfiles(node, profiles)
+	if err != nil {
+		return nil, comp.translate(err)
+	}
+
+	return node, nil
+}
+
+// add adds a file, and all the files it includes, into the composed
+// document. Included files come first, allowing the file to override
+// them, and to refer to their anchors.
+func (comp *composer) add(filename string, stack []string) error {
+	for _, item := range stack {
+		if item == filename {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), filename)
+		}
+	}
+
+	if comp.loaded[filename] {
+		return nil
+	}
+
+	comp.loaded[filename] = true
+
+	content, err := afero.ReadFile(comp.fs, filename)
+	if err != nil {
+		return fmt.Errorf("loading GoShipDone file: %w", err)
+	}
+
+	includes, err := findIncludes(filename, content)
+	if err != nil {
+		return err
+	}
+
+	for _, include := range includes {
+		if err := comp.add(include, append(stack, filename)); err != nil {
+			return err
+		}
+	}
+
+	comp.append(filename, content)
+
+	return nil
+}
+
+// append writes file contents into the composed document, indented under
+// a synthetic key. Document markers are blanked, keeping line numbers
+// intact.
+func (comp *composer) append(filename string, content []byte) {
+	src := &source{
+		filename: filename,
+		key:      fmt.Sprintf("__goshipdone_file_%d", len(comp.sources)),
+		start:    comp.lines + 2,
+	}
+
+	fmt.Fprintf(&comp.document, "%s:\n", src.key)
+	comp.lines++
+
+	indent := strings.Repeat(" ", fileIndent)
+
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		if isDocumentMarker(line) {
+			line = ""
+		}
+
+		if line == "" {
+			comp.document.WriteString("\n")
+		} else {
+			fmt.Fprintf(&comp.document, "%s%s\n", indent, line)
+		}
+
+		src.lines++
+		comp.lines++
+	}
+
+	comp.sources = append(comp.sources, src)
+}
+
+// merge parses the composed document, and deep-merges file contents
+func (comp *composer) merge() (*yaml.Node, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(comp.document.Bytes(), &doc); err != nil {
+		return nil, syntaxError(err)
+	}
+
+	if len(doc.Content) == 0 {
+		return &yaml.Node{}, nil
+	}
+
+	var merged *yaml.Node
+
+	root := doc.Content[0]
+
+	for idx := 1; idx < len(root.Content); idx += 2 {
+		node := resolve(root.Content[idx])
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			continue
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return nil, modules.NewConfigError(node, errors.New("configuration is not a map"))
+		}
+
+		var err error
+
+		merged, err = mergeConfig(merged, withoutKey(node, includeKey))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if merged == nil {
+		return &yaml.Node{}, nil
+	}
+
+	return merged, nil
+}
+
+// translate moves positions of configuration errors from the composed
+// document to their source files.
+func (comp *composer) translate(err error) error {
+	problems, ok := err.(modules.Errors)
+	if !ok {
+		problems = modules.Errors{err}
+	}
+
+	for _, problem := range problems {
+		if nested, ok := problem.(modules.Errors); ok {
+			comp.translate(nested)
+
+			continue
+		}
+
+		var configErr *modules.ConfigError
+		if !errors.As(problem, &configErr) || configErr.File != "" || configErr.Line == 0 {
+			continue
+		}
+
+		for _, src := range comp.sources {
+			if configErr.Line >= src.start && configErr.Line < src.start+src.lines {
+				configErr.File = src.filename
+				configErr.Line -= src.start - 1
+
+				if configErr.Column > fileIndent {
+					configErr.Column -= fileIndent
+				}
+
+				break
+			}
+		}
+	}
+
+	return err
+}
+
+// applyProfiles merges the selected profiles on top of the configuration.
+// Profiles are defined in the `profiles` section, by their names.
+func applyProfiles(node *yaml.Node, profiles []string) (*yaml.Node, error) {
+	var defined *yaml.Node
+
+	if key := findValue(node, profilesKey); key != nil {
+		defined = resolve(key)
+		node = withoutKey(node, profilesKey)
+	}
+
+	for _, profile := range profiles {
+		overlay := findValue(defined, profile)
+		if overlay == nil {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+
+		overlay = resolve(overlay)
+		if overlay.Kind != yaml.MappingNode {
+			return nil, modules.NewConfigError(overlay, fmt.Errorf("profile %s is not a map", profile))
+		}
+
+		var err error
+
+		node, err = mergeConfig(node, overlay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// mergeConfig returns overlay configuration deep-merged on top of base,
+// like mergeNodes. Modules of stages are matched by their effective IDs:
+// their `id` keys, or the default IDs of their types. Overlay modules
+// with an `id`, but without a `type` must override an existing module.
+func mergeConfig(base, overlay *yaml.Node) (*yaml.Node, error) {
+	if base == nil {
+		return overlay, nil
+	}
+
+	base = resolve(base)
+	if base.Kind != yaml.MappingNode {
+		return mergeNodes(base, overlay), nil
+	}
+
+	stages := pipeline.BuildStageNames(mergeNodes(base, overlay))
+	merged := *base
+	merged.Content = append([]*yaml.Node{}, base.Content...)
+
+	var errs modules.Errors
+
+	for idx := 0; idx+1 < len(overlay.Content); idx += 2 {
+		key := overlay.Content[idx]
+		value := overlay.Content[idx+1]
+		pos := keyIndex(&merged, key.Value)
+
+		if pos < 0 || key.Tag == "!!merge" {
+			merged.Content = append(merged.Content, key, value)
+
+			continue
+		}
+
+		stage, ok := stages[key.Value]
+		if !ok || resolve(merged.Content[pos+1]).Kind != yaml.SequenceNode ||
+			resolve(value).Kind != yaml.SequenceNode || !isMapSequence(resolve(value)) {
+			merged.Content[pos+1] = mergeNodes(merged.Content[pos+1], value)
+
+			continue
+		}
+
+		node, err := mergeModules(stage, merged.Content[pos+1], value)
+		errs.Extend(err)
+
+		merged.Content[pos+1] = node
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// mergeModules returns overlay modules of a stage deep-merged on top of
+// base modules, matching their effective IDs.
+func mergeModules(stage string, base, overlay *yaml.Node) (*yaml.Node, error) {
+	base = resolve(base)
+	merged := *base
+	merged.Content = append([]*yaml.Node{}, base.Content...)
+
+	var errs modules.Errors
+
+	for _, item := range resolve(overlay).Content {
+		id := findValue(resolve(item), mergeKey)
+		if id == nil {
+			merged.Content = append(merged.Content, item)
+
+			continue
+		}
+
+		pos := moduleIndex(stage, &merged, resolve(id).Value)
+		if pos >= 0 {
+			merged.Content[pos] = mergeNodes(merged.Content[pos], item)
+
+			continue
+		}
+
+		if findValue(resolve(item), typeKey) == nil {
+			errs.Add(modules.NewConfigError(id, fmt.Errorf("no module with id %q to override", resolve(id).Value)))
+
+			continue
+		}
+
+		merged.Content = append(merged.Content, item)
+	}
+
+	return &merged, errs.Err()
+}
+
+// mergeNodes returns overlay deep-merged on top of base, without modifying
+// either of them. Maps are merged key by key. Sequences of maps are merged
+// item by item, matching their `id` keys, and appending items without a
+// match. Other sequences, and all other values are replaced.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil {
+		return overlay
+	}
+
+	base = resolve(base)
+	overlay = resolve(overlay)
+
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		merged := *base
+		merged.Content = append([]*yaml.Node{}, base.Content...)
+
+		for idx := 0; idx+1 < len(overlay.Content); idx += 2 {
+			key := overlay.Content[idx]
+			pos := keyIndex(&merged, key.Value)
+
+			if pos < 0 || key.Tag == "!!merge" {
+				merged.Content = append(merged.Content, key, overlay.Content[idx+1])
+
+				continue
+			}
+
+			merged.Content[pos+1] = mergeNodes(merged.Content[pos+1], overlay.Content[idx+1])
+		}
+
+		return &merged
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && isMapSequence(overlay):
+		merged := *base
+		merged.Content = append([]*yaml.Node{}, base.Content...)
+
+		for _, item := range overlay.Content {
+			pos := idIndex(&merged, findValue(resolve(item), mergeKey))
+			if pos < 0 {
+				merged.Content = append(merged.Content, item)
+
+				continue
+			}
+
+			merged.Content[pos] = mergeNodes(merged.Content[pos], item)
+		}
+
+		return &merged
+	}
+
+	return overlay
+}
+
+// findIncludes returns files included by a configuration file. It reads
+// the top-level `include` section textually, as the file might refer to
+// anchors defined in other files, therefore it cannot be parsed alone.
+func findIncludes(filename string, content []byte) ([]string, error) {
+	lines := strings.Split(string(content), "\n")
+	start := -1
+
+	var section []string
+
+	for idx, line := range lines {
+		if start < 0 {
+			if strings.HasPrefix(line, includeKey+":") {
+				start = idx
+				section = append(section, line)
+			}
+
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") &&
+			!strings.HasPrefix(line, "#") {
+			break
+		}
+
+		section = append(section, line)
+	}
+
+	if start < 0 {
+		return nil, nil
+	}
+
+	var parsed struct {
+		Include includeList `yaml:"include"`
+	}
+
+	if err := yaml.Unmarshal([]byte(strings.Join(section, "\n")), &parsed); err != nil {
+		return nil, &modules.ConfigError{
+			File:   filename,
+			Line:   start + 1,
+			Column: 1,
+			Err:    fmt.Errorf("invalid include: %w", err),
+		}
+	}
+
+	includes := make([]string, 0, len(parsed.Include))
+
+	for _, include := range parsed.Include {
+		if strings.HasPrefix(include, "file://") {
+			include = strings.TrimPrefix(include, "file://")
+		} else if schemeRE.MatchString(include) {
+			return nil, &modules.ConfigError{
+				File:   filename,
+				Line:   start + 1,
+				Column: 1,
+				Err:    fmt.Errorf("unsupported include URL: %s", include),
+			}
+		}
+
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+
+		includes = append(includes, include)
+	}
+
+	return includes, nil
+}
+
+// syntaxError converts YAML syntax errors into ConfigError
+func syntaxError(err error) error {
+	match := syntaxErrorRE.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(match[1])
+
+	return &modules.ConfigError{Line: line, Column: fileIndent + 1, Err: errors.New(match[2])}
+}
+
+func isDocumentMarker(line string) bool {
+	for _, marker := range []string{"---", "..."} {
+		if line == marker || strings.HasPrefix(line, marker+" ") {
+			return true
+		}
+	}
+
+	return strings.HasPrefix(line, "%")
+}
+
+func isMapSequence(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if resolve(item).Kind != yaml.MappingNode {
+			return false
+		}
+	}
+
+	return true
+}
+
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// keyIndex returns the index of a key in a mapping node, or -1
+func keyIndex(node *yaml.Node, key string) int {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key && node.Content[idx].Tag != "!!merge" {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// findValue returns the value of a key in a mapping node, or nil
+func findValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	if idx := keyIndex(node, key); idx >= 0 {
+		return node.Content[idx+1]
+	}
+
+	return nil
+}
+
+// idIndex returns the index of the sequence item with the same id, or -1
+func idIndex(node *yaml.Node, id *yaml.Node) int {
+	if id == nil {
+		return -1
+	}
+
+	for idx, item := range node.Content {
+		if itemID := findValue(resolve(item), mergeKey); itemID != nil && resolve(itemID).Value == resolve(id).Value {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// moduleIndex returns the index of the module with an effective ID in a
+// stage's sequence, or -1
+func moduleIndex(stage string, node *yaml.Node, id string) int {
+	for idx, item := range node.Content {
+		item = resolve(item)
+		itemID := findValue(item, mergeKey)
+
+		if itemID != nil {
+			if resolve(itemID).Value == id {
+				return idx
+			}
+
+			continue
+		}
+
+		if typ := findValue(item, typeKey); typ != nil && modules.DefaultID(stage, resolve(typ).Value) == id {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// withoutKey returns a copy of a mapping node without a key
+func withoutKey(node *yaml.Node, key string) *yaml.Node {
+	idx := keyIndex(node, key)
+	if idx < 0 {
+		return node
+	}
+
+	stripped := *node
+	stripped.Content = append(append([]*yaml.Node{}, node.Content[:idx]...), node.Content[idx+2:]...)
+
+	return &stripped
+}
@@ -0,0 +1,198 @@
+package goshipdone
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: funlen
+func Test_composer_compose(t *testing.T) {
+	files := map[string]string{
+		"common/defaults.yml": `---
+builds:
+- type: go
+  id: app
+  goos: &oses
+  - linux
+  - darwin
+  goarch: [amd64]
+- type: tar
+  id: archive
+  builds: [app]
+`,
+		".goshipdone.yml": `---
+include: common/defaults.yml
+setups:
+- type: project
+  name: demo
+builds:
+- type: checksum
+  builds: [archive]
+  skip: *oses
+profiles:
+  nightly:
+    builds:
+    - id: app
+      goarch: [arm64]
+`,
+		".goshipdone.local.yml": `---
+builds:
+- id: archive
+  compression: gzip
+`,
+		"defaults.yml": `---
+builds:
+- type: go
+  goos: [linux]
+profiles:
+  nightly:
+    builds:
+    - id: default
+      goarch: [arm64]
+  broken:
+    builds:
+    - id: app
+      goarch: [arm64]
+`,
+		"cycle-a.yml": "include: cycle-b.yml\n",
+		"cycle-b.yml": "include:\n- file://cycle-a.yml\n",
+		"remote.yml":  "include: https://example.com/config.yml\n",
+		"broken.yml":  "include: common/defaults.yml\nbuilds:\n- type: go\n  goos: [linux\n",
+	}
+
+	tests := []struct {
+		name      string
+		filenames []string
+		profiles  []string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "include with shared anchors",
+			filenames: []string{".goshipdone.yml"},
+			want: `
+setups: [{type: project, name: demo}]
+builds:
+- {type: go, id: app, goos: [linux, darwin], goarch: [amd64]}
+- {type: tar, id: archive, builds: [app]}
+- {type: checksum, builds: [archive], skip: [linux, darwin]}
+`,
+		},
+		{
+			name:      "local overlay",
+			filenames: []string{".goshipdone.yml", ".goshipdone.local.yml"},
+			want: `
+setups: [{type: project, name: demo}]
+builds:
+- {type: go, id: app, goos: [linux, darwin], goarch: [amd64]}
+- {type: tar, id: archive, builds: [app], compression: gzip}
+- {type: checksum, builds: [archive], skip: [linux, darwin]}
+`,
+		},
+		{
+			name:      "profile",
+			filenames: []string{".goshipdone.yml"},
+			profiles:  []string{"nightly"},
+			want: `
+setups: [{type: project, name: demo}]
+builds:
+- {type: go, id: app, goos: [linux, darwin], goarch: [arm64]}
+- {type: tar, id: archive, builds: [app]}
+- {type: checksum, builds: [archive], skip: [linux, darwin]}
+`,
+		},
+		{
+			name:      "unknown profile",
+			filenames: []string{".goshipdone.yml"},
+			profiles:  []string{"weekly"},
+			wantErr:   `unknown profile "weekly"`,
+		},
+		{
+			name:      "profile overriding default ID",
+			filenames: []string{"defaults.yml"},
+			profiles:  []string{"nightly"},
+			want: `
+builds:
+- {type: go, goos: [linux], id: default, goarch: [arm64]}
+`,
+		},
+		{
+			name:      "profile overriding unknown ID",
+			filenames: []string{"defaults.yml"},
+			profiles:  []string{"broken"},
+			wantErr:   `defaults.yml: line 12, column 11: no module with id "app" to override`,
+		},
+		{
+			name:      "include cycle",
+			filenames: []string{"cycle-a.yml"},
+			wantErr:   "include cycle: cycle-a.yml -> cycle-b.yml -> cycle-a.yml",
+		},
+		{
+			name:      "unsupported URL",
+			filenames: []string{"remote.yml"},
+			wantErr:   "remote.yml: line 1, column 1: unsupported include URL: https://example.com/config.yml",
+		},
+		{
+			name:      "syntax error",
+			filenames: []string{"broken.yml"},
+			wantErr:   "broken.yml: line 3, column 1: did not find expected ',' or ']'",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for filename, content := range files {
+				_ = afero.WriteFile(fs, filename, []byte(content), 0o644)
+			}
+
+			node, err := newComposer(fs).compose(tt.filenames, tt.profiles)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("composer.compose() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("composer.compose() unexpected error: %v", err)
+			}
+
+			var got, want interface{}
+			if err := node.Decode(&got); err != nil {
+				t.Fatalf("decoding composed node: %v", err)
+			}
+
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("decoding expected result: %v", err)
+			}
+
+			if diff := deep.Equal(got, want); diff != nil {
+				t.Errorf("composer.compose() %v", diff)
+			}
+		})
+	}
+}
+
+func TestLoad_errorPositions(t *testing.T) {
+	origFS := defaultFS
+	defaultFS = afero.NewMemMapFs()
+
+	defer func() { defaultFS = origFS }()
+
+	_ = afero.WriteFile(defaultFS, "base.yml", []byte("---\nbuilds:\n- type: go\n  goarhc: [amd64]\n"), 0o644)
+	_ = afero.WriteFile(defaultFS, "main.yml", []byte("include: base.yml\npublishes:\n- type: shw\n"), 0o644)
+
+	_, err := Load("main.yml")
+
+	want := "processing GoShipDone file: base.yml: line 4, column 3: module build:go: unknown field \"goarhc\"\n" +
+		"main.yml: line 3, column 3: unknown module publish:shw"
+	if err == nil || err.Error() != want {
+		t.Errorf("Load() error = %v, want %q", err, want)
+	}
+}
@@ -1,30 +1,152 @@
 package ctx
 
 import (
-	"log"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
 )
 
 type (
-	// Artifacts is a slice of Artifact
+	// Artifacts is a slice of Artifact. It is not safe for concurrent use,
+	// see Context for artifacts of a pipeline run.
 	Artifacts []*Artifact
 
 	// Artifact is a file generated by the build pipeline, which can
 	// be further processed by later steps (eg. a build result put into
-	// an archive)
+	// an archive). Kind tells what the artifact is, Size is the size of
+	// its file, and Extra contains free-form attributes modules can set
+	// for later ones.
 	Artifact struct {
-		*OsArch
-		Filename string
-		ID       string
-		Location string
+		*OsArch  `json:"osarch,omitempty"`
+		Filename string                 `json:"filename"`
+		ID       string                 `json:"id"`
+		Kind     ArtifactKind           `json:"kind,omitempty"`
+		Location string                 `json:"location"`
+		Size     int64                  `json:"size,omitempty"`
+		Extra    map[string]interface{} `json:"extra,omitempty"`
+		// digestsLock protects the digest cache
+		digestsLock sync.Mutex
+		digests     *digestCache
+	}
+
+	// digestCache contains digests of an artifact's file, as long as the
+	// file has the same size and modification time
+	digestCache struct {
+		size    int64
+		modTime time.Time
+		sums    map[crypto.Hash]string
 	}
 )
 
 // Add registers a new artifact in Artifacts
 func (arts *Artifacts) Add(artifact *Artifact) {
-	log.Printf("      storing artifact %s as %s (%s)", artifact.Filename, artifact.ID, artifact.OsArch.String())
 	*arts = append(*arts, artifact)
 }
 
+// Remove removes an artifact from Artifacts. It returns false, if the
+// artifact is not found.
+func (arts *Artifacts) Remove(artifact *Artifact) bool {
+	for idx, art := range *arts {
+		if art == artifact {
+			*arts = append((*arts)[:idx], (*arts)[idx+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stat updates Size of the artifact from its file
+func (art *Artifact) Stat() error {
+	info, err := os.Stat(art.Location)
+	if err != nil {
+		return fmt.Errorf("artifact %s: %w", art.Filename, err)
+	}
+
+	art.Size = info.Size()
+
+	return nil
+}
+
+// Digest returns the digest of the artifact's file in hex format, with a
+// hash algorithm, like crypto.SHA256. Digests are calculated on first
+// use, and they are cached until the file changes. It is safe for
+// concurrent use.
+func (art *Artifact) Digest(hash crypto.Hash) (string, error) {
+	if !hash.Available() {
+		return "", fmt.Errorf("hash algorithm %v is not available", hash)
+	}
+
+	info, err := os.Stat(art.Location)
+	if err != nil {
+		return "", fmt.Errorf("artifact %s: %w", art.Filename, err)
+	}
+
+	art.digestsLock.Lock()
+	cache := art.digests
+
+	if cache != nil && cache.size == info.Size() && cache.modTime.Equal(info.ModTime()) {
+		if sum, ok := cache.sums[hash]; ok {
+			art.digestsLock.Unlock()
+
+			return sum, nil
+		}
+	}
+	art.digestsLock.Unlock()
+
+	sum, err := fileDigest(art.Location, hash)
+	if err != nil {
+		return "", fmt.Errorf("artifact %s: %w", art.Filename, err)
+	}
+
+	art.digestsLock.Lock()
+	defer art.digestsLock.Unlock()
+
+	cache = art.digests
+	if cache == nil || cache.size != info.Size() || !cache.modTime.Equal(info.ModTime()) {
+		cache = &digestCache{size: info.Size(), modTime: info.ModTime(), sums: map[crypto.Hash]string{}}
+		art.digests = cache
+	}
+
+	cache.sums[hash] = sum
+
+	return sum, nil
+}
+
+// fileDigest returns the digest of a file in hex format
+func fileDigest(filename string, hash crypto.Hash) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := hash.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ByKind searches artifacts by their kinds
+func (arts *Artifacts) ByKind(kind ArtifactKind) *Artifacts {
+	results := &Artifacts{}
+
+	for _, art := range *arts {
+		if art.Kind == kind {
+			*results = append(*results, art)
+		}
+	}
+
+	return results
+}
+
 // ByID searches artifacts by their build IDs
 func (arts *Artifacts) ByID(id string) *Artifacts {
 	results := &Artifacts{}
@@ -38,30 +160,14 @@ func (arts *Artifacts) ByID(id string) *Artifacts {
 	return results
 }
 
-// OsArchByIDs maps artifacts by OS-Arch, filtering by IDs
+// OsArchByIDs maps artifacts by OS-Arch, filtering by IDs, and skipping
+// OS-arch names
 func (arts *Artifacts) OsArchByIDs(ids []string, skips []string) map[string]*Artifacts {
-	skipIndex := make(map[string]bool, len(skips))
+	filters := make([]*ArtifactFilter, 0, len(skips))
 
 	for _, skip := range skips {
-		skipIndex[skip] = true
-	}
-
-	builds := map[string]*Artifacts{}
-
-	for _, id := range ids {
-		for _, art := range *arts.ByID(id) {
-			osarch := art.OsArch.String()
-			if _, ok := skipIndex[osarch]; ok {
-				continue
-			}
-
-			if _, ok := builds[osarch]; !ok {
-				builds[osarch] = &Artifacts{}
-			}
-
-			*builds[osarch] = append(*builds[osarch], art)
-		}
+		filters = append(filters, &ArtifactFilter{OsArch: skip})
 	}
 
-	return builds
+	return arts.OsArchBySelector(IDSelector(ids...).Without(filters...))
 }
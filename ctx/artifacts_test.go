@@ -1,7 +1,15 @@
 package ctx
 
 import (
+	"crypto"
+	_ "crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestArtifacts_Add(t *testing.T) {
@@ -85,3 +93,122 @@ func TestArtifacts_ByID(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifacts_ByKind(t *testing.T) {
+	arts := Artifacts{
+		&Artifact{ID: "default", Kind: KindBinary},
+		&Artifact{ID: "archive", Kind: KindArchive},
+		&Artifact{ID: "changes", Kind: KindReleaseNotes},
+		&Artifact{ID: "other", Kind: KindBinary},
+	}
+
+	got := []string{}
+	for _, art := range *arts.ByKind(KindBinary) {
+		got = append(got, art.ID)
+	}
+
+	if diff := deep.Equal(got, []string{"default", "other"}); diff != nil {
+		t.Errorf("Artifacts.ByKind() %v", diff)
+	}
+}
+
+func TestArtifact_Digest(t *testing.T) {
+	filename := path.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(filename, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	art := &Artifact{ID: "file", Filename: "file", Location: filename}
+
+	sum, err := art.Digest(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Artifact.Digest() error = %v", err)
+	}
+
+	if want := "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e"; sum != want {
+		t.Errorf("Artifact.Digest() = %s, want %s", sum, want)
+	}
+
+	art.digests.sums[crypto.SHA256] = "cached"
+
+	if sum, _ := art.Digest(crypto.SHA256); sum != "cached" {
+		t.Errorf("Artifact.Digest() = %s, want cached digest", sum)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("second file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if sum, _ := art.Digest(crypto.SHA256); sum == "cached" {
+		t.Errorf("Artifact.Digest() returned cached digest of a changed file")
+	}
+
+	if err := art.Stat(); err != nil || art.Size != 11 {
+		t.Errorf("Artifact.Stat() size = %d, error = %v, want size 11", art.Size, err)
+	}
+}
+
+func TestArtifactKind_Text(t *testing.T) {
+	for _, name := range ArtifactKindNames() {
+		kind, err := ParseArtifactKind(name)
+		if err != nil {
+			t.Errorf("ParseArtifactKind(%q) error = %v", name, err)
+			continue
+		}
+
+		if kind.String() != name {
+			t.Errorf("ParseArtifactKind(%q).String() = %q", name, kind.String())
+		}
+	}
+
+	if _, err := ParseArtifactKind("unknown"); err == nil {
+		t.Errorf("ParseArtifactKind(\"unknown\") returned no error")
+	}
+}
+
+func TestContext_AddArtifactConcurrently(t *testing.T) {
+	const workers = 16
+
+	context := &Context{}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(idx int) {
+			defer wg.Done()
+
+			context.AddArtifact(&Artifact{
+				ID:       "default",
+				Location: fmt.Sprintf("dist/default-%d", idx),
+				Filename: "default",
+				OsArch:   &OsArch{OS: "linux", Arch: "amd64"},
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(context.Artifacts) != workers {
+		t.Errorf("Context.AddArtifact() yielded %d artifacts, wants = %d", len(context.Artifacts), workers)
+	}
+}
+
+func TestContext_AddArtifact(t *testing.T) {
+	var added []string
+
+	context := &Context{}
+	context.AddArtifact(&Artifact{ID: "unobserved"})
+
+	context.OnArtifact = func(artifact *Artifact) { added = append(added, artifact.ID) }
+	context.AddArtifact(&Artifact{ID: "observed"})
+
+	if len(context.Artifacts) != 2 {
+		t.Errorf("Context.AddArtifact() yielded %d artifacts, wants = 2", len(context.Artifacts))
+	}
+
+	if diff := deep.Equal(added, []string{"observed"}); diff != nil {
+		t.Errorf("Context.AddArtifact() %v", diff)
+	}
+}
@@ -5,35 +5,52 @@ package ctx
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/julian7/withenv"
 )
 
+// SourceDateEpochVar is the environment variable of the timestamp
+// reproducible builds use, in Unix time format
+const SourceDateEpochVar = "SOURCE_DATE_EPOCH"
+
 type info struct{}
 
 var Info = &info{}
 
 // Context are a cumulative structure carried over to each module,
-// to contain data later steps might require
+// to contain data later steps might require. As modules may run
+// concurrently, Artifacts should be accessed through AddArtifact,
+// RemoveArtifact, and ListArtifacts. OnArtifact, if set, is called with
+// each artifact registered with AddArtifact.
 type Context struct {
 	context.Context
 	Artifacts   Artifacts
 	Env         *withenv.Env
 	Git         *GitData
+	OnArtifact  func(*Artifact)
 	ProjectName string
 	Publish     bool
 	TargetDir   string
 	Version     string
+	// artifactsLock protects Artifacts
+	artifactsLock sync.RWMutex
 }
 
 // GitData contains git-specific information on the repository
 type GitData struct {
 	// Tag contains git tag information, if the repo is on a specific tag
-	Tag string
+	Tag string `json:"tag,omitempty"`
 	// Ref contains the full SHA1 checksum of the current commit
-	Ref string
+	Ref string `json:"ref,omitempty"`
 	// URL contains git repo's URL, collected from current branch's upstream
-	URL string
+	URL string `json:"url,omitempty"`
+	// CommitTimestamp contains the commit time of the current commit, in
+	// Unix time format
+	CommitTimestamp int64 `json:"commit_timestamp,omitempty"`
 }
 
 func New(ctx context.Context) context.Context {
@@ -57,3 +74,55 @@ func GetShipContext(cx context.Context) (*Context, error) {
 
 	return context, nil
 }
+
+// AddArtifact registers a new artifact in Artifacts, and calls OnArtifact
+// with it, if set. It is safe for concurrent use.
+func (c *Context) AddArtifact(artifact *Artifact) {
+	c.artifactsLock.Lock()
+	c.Artifacts.Add(artifact)
+	c.artifactsLock.Unlock()
+
+	if c.OnArtifact != nil {
+		c.OnArtifact(artifact)
+	}
+}
+
+// RemoveArtifact removes an artifact from Artifacts. It returns false, if
+// the artifact is not found. It is safe for concurrent use.
+func (c *Context) RemoveArtifact(artifact *Artifact) bool {
+	c.artifactsLock.Lock()
+	defer c.artifactsLock.Unlock()
+
+	return c.Artifacts.Remove(artifact)
+}
+
+// ListArtifacts returns a copy of Artifacts. It is safe for concurrent
+// use.
+func (c *Context) ListArtifacts() *Artifacts {
+	c.artifactsLock.RLock()
+	defer c.artifactsLock.RUnlock()
+
+	arts := append(Artifacts{}, c.Artifacts...)
+
+	return &arts
+}
+
+// SourceDate returns the timestamp of reproducible builds: the value of
+// SOURCE_DATE_EPOCH environment variable, or the commit time of the
+// current commit. It returns zero time if neither is known.
+func (c *Context) SourceDate() (time.Time, error) {
+	if value, ok := c.Env.Get(SourceDateEpochVar); ok && value != "" {
+		epoch, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s %q: must be a Unix timestamp", SourceDateEpochVar, value)
+		}
+
+		return time.Unix(epoch, 0).UTC(), nil
+	}
+
+	if c.Git != nil && c.Git.CommitTimestamp != 0 {
+		return time.Unix(c.Git.CommitTimestamp, 0).UTC(), nil
+	}
+
+	return time.Time{}, nil
+}
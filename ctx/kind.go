@@ -0,0 +1,86 @@
+package ctx
+
+import "fmt"
+
+// Artifact kinds
+const (
+	// KindUnknown is an artifact of unspecified kind
+	KindUnknown ArtifactKind = iota
+	// KindBinary is an executable, or a library built
+	KindBinary
+	// KindArchive is an archive of other artifacts, like a tarball
+	KindArchive
+	// KindChecksum is a checksum file of other artifacts
+	KindChecksum
+	// KindSignature is a signature of other artifacts
+	KindSignature
+	// KindReleaseNotes is a release notes document, like a changelog
+	KindReleaseNotes
+	// KindPackage is an OS package, like a deb, or an rpm
+	KindPackage
+	// KindSBOM is a software bill of materials
+	KindSBOM
+	// KindImage is a container, or a disk image
+	KindImage
+)
+
+// ArtifactKind is the kind of an artifact, telling downstream modules
+// what it is. It is represented by its name in JSON.
+type ArtifactKind int
+
+// nolint: gochecknoglobals
+var kindNames = []string{
+	"",
+	"binary",
+	"archive",
+	"checksum",
+	"signature",
+	"release_notes",
+	"package",
+	"sbom",
+	"image",
+}
+
+// String returns the name of the artifact kind, or an empty string for
+// KindUnknown
+func (kind ArtifactKind) String() string {
+	if kind < 0 || int(kind) >= len(kindNames) {
+		return ""
+	}
+
+	return kindNames[kind]
+}
+
+// MarshalText returns the name of the artifact kind
+func (kind ArtifactKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// UnmarshalText parses an artifact kind by its name
+func (kind *ArtifactKind) UnmarshalText(text []byte) error {
+	parsed, err := ParseArtifactKind(string(text))
+	if err != nil {
+		return err
+	}
+
+	*kind = parsed
+
+	return nil
+}
+
+// ParseArtifactKind returns an artifact kind by its name. An empty name
+// is KindUnknown.
+func ParseArtifactKind(name string) (ArtifactKind, error) {
+	for idx, kindName := range kindNames {
+		if kindName == name {
+			return ArtifactKind(idx), nil
+		}
+	}
+
+	return KindUnknown, fmt.Errorf("unknown artifact kind %q", name)
+}
+
+// ArtifactKindNames returns names of all known artifact kinds
+func ArtifactKindNames() []string {
+	return append([]string{}, kindNames[1:]...)
+}
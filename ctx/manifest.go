@@ -0,0 +1,188 @@
+package ctx
+
+import (
+	"crypto"
+	// registers SHA256 for manifest checksums
+	_ "crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ManifestFilename is the name of the file in the target directory, where
+// the manifest of the last run is saved.
+const ManifestFilename = "metadata.json"
+
+type (
+	// Manifest describes a run: the project, its version, git
+	// information, all artifacts with their sizes and checksums, and
+	// timings of stages and modules. It is written into the target
+	// directory, to be read by other tools, or by later runs.
+	Manifest struct {
+		ProjectName string              `json:"project_name"`
+		Version     string              `json:"version"`
+		Git         *GitData            `json:"git"`
+		Artifacts   []*ManifestArtifact `json:"artifacts"`
+		Timings     []*Timing           `json:"timings"`
+	}
+
+	// ManifestArtifact is an artifact in the manifest, with its checksum
+	// in `algorithm:hex` format
+	ManifestArtifact struct {
+		*Artifact
+		Checksum string `json:"checksum"`
+	}
+
+	// Timing is the time spent on a stage, or on a module, if Module is
+	// set. Status is "done", "failed", or "skipped".
+	Timing struct {
+		Stage   string  `json:"stage"`
+		Module  string  `json:"module,omitempty"`
+		Status  string  `json:"status"`
+		Seconds float64 `json:"seconds"`
+	}
+)
+
+// NewManifest returns the manifest of a context, with timings provided.
+// It reads all artifacts to calculate their checksums.
+func NewManifest(context *Context, timings []*Timing) (*Manifest, error) {
+	arts := *context.ListArtifacts()
+
+	manifest := &Manifest{
+		ProjectName: context.ProjectName,
+		Version:     context.Version,
+		Git:         context.Git,
+		Artifacts:   make([]*ManifestArtifact, 0, len(arts)),
+		Timings:     timings,
+	}
+
+	for _, artifact := range arts {
+		if err := artifact.Stat(); err != nil {
+			return nil, err
+		}
+
+		digest, err := artifact.Digest(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Artifacts = append(manifest.Artifacts, &ManifestArtifact{
+			Artifact: artifact,
+			Checksum: "sha256:" + digest,
+		})
+	}
+
+	return manifest, nil
+}
+
+// LoadManifest reads a manifest written by Manifest.Save
+func LoadManifest(filename string) (*Manifest, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest %s: %w", filename, err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest %s: %w", filename, err)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest into a file in JSON format. The file is
+// replaced atomically, as it is rewritten after each stage.
+func (manifest *Manifest) Save(filename string) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	tmpFilename := filename + ".tmp"
+
+	if err := os.WriteFile(tmpFilename, content, 0o644); err != nil { // nolint: gosec
+		return fmt.Errorf("writing manifest %s: %w", filename, err)
+	}
+
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		_ = os.Remove(tmpFilename)
+		return fmt.Errorf("writing manifest %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// Restore loads the manifest into a context: project name, version, git
+// information, and artifacts are replaced.
+func (manifest *Manifest) Restore(context *Context) {
+	context.ProjectName = manifest.ProjectName
+	context.Version = manifest.Version
+
+	if manifest.Git != nil {
+		git := *manifest.Git
+		context.Git = &git
+	}
+
+	arts := make(Artifacts, 0, len(manifest.Artifacts))
+	for _, artifact := range manifest.Artifacts {
+		arts = append(arts, artifact.Artifact)
+	}
+
+	context.artifactsLock.Lock()
+	context.Artifacts = arts
+	context.artifactsLock.Unlock()
+}
+
+// Verify checks all artifacts of the manifest against their files: they
+// must exist, with the same size and checksum. It reports all problems at
+// once.
+func (manifest *Manifest) Verify() error {
+	problems := []string{}
+
+	for _, artifact := range manifest.Artifacts {
+		size, checksum, err := fileChecksum(artifact.Location)
+
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("artifact %s: %v", artifact.Filename, err))
+		case size != artifact.Size:
+			problems = append(problems, fmt.Sprintf(
+				"artifact %s: size is %d, expected %d",
+				artifact.Filename,
+				size,
+				artifact.Size,
+			))
+		case checksum != artifact.Checksum:
+			problems = append(problems, fmt.Sprintf(
+				"artifact %s: checksum is %s, expected %s",
+				artifact.Filename,
+				checksum,
+				artifact.Checksum,
+			))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// fileChecksum returns size and SHA256 checksum of a file in manifest
+// format, without using the artifact's digest cache
+func fileChecksum(filename string) (int64, string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, "", err
+	}
+
+	checksum, err := fileDigest(filename, crypto.SHA256)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return info.Size(), "sha256:" + checksum, nil
+}
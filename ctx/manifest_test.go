@@ -0,0 +1,72 @@
+package ctx
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestManifest_SaveLoadRestore(t *testing.T) {
+	dir := t.TempDir()
+	location := path.Join(dir, "binary")
+
+	if err := os.WriteFile(location, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext := &Context{
+		ProjectName: "project",
+		Version:     "1.2.3",
+		Git:         &GitData{Tag: "v1.2.3", Ref: "abcdef", URL: "git@example.com:project.git"},
+		Artifacts: Artifacts{
+			{ID: "default", Filename: "binary", Location: location, OsArch: &OsArch{OS: "linux", Arch: "arm", Variant: "7"}},
+		},
+	}
+	timings := []*Timing{{Stage: "build", Module: "go", Status: "done", Seconds: 1.5}}
+
+	manifest, err := NewManifest(shipContext, timings)
+	if err != nil {
+		t.Fatalf("NewManifest() error = %v", err)
+	}
+
+	want := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if art := manifest.Artifacts[0]; art.Size != 5 || art.Checksum != want {
+		t.Errorf("NewManifest() artifact size %d, checksum %s, want 5, %s", art.Size, art.Checksum, want)
+	}
+
+	filename := path.Join(dir, ManifestFilename)
+
+	if err := manifest.Save(filename); err != nil {
+		t.Fatalf("Manifest.Save() error = %v", err)
+	}
+
+	loaded, err := LoadManifest(filename)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+
+	if diff := deep.Equal(loaded, manifest); diff != nil {
+		t.Errorf("LoadManifest() %v", diff)
+	}
+
+	restored, err := GetShipContext(New(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded.Restore(restored)
+
+	for name, pair := range map[string][2]interface{}{
+		"project name": {restored.ProjectName, shipContext.ProjectName},
+		"version":      {restored.Version, shipContext.Version},
+		"git":          {restored.Git, shipContext.Git},
+		"artifacts":    {restored.Artifacts, shipContext.Artifacts},
+	} {
+		if diff := deep.Equal(pair[0], pair[1]); diff != nil {
+			t.Errorf("Manifest.Restore() %s %v", name, diff)
+		}
+	}
+}
@@ -1,19 +1,99 @@
 package ctx
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type OsArch struct {
-	OS         string
-	Arch       string
-	ArmVersion int32
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// Variant is the microarchitecture variant of the architecture, set
+	// in its variant variable (see VariantVar), like 7 for GOARM, v3 for
+	// GOAMD64, or softfloat for GOMIPS.
+	Variant string `json:"variant,omitempty"`
 }
 
+// nolint: gochecknoglobals
+var variantVars = map[string]string{
+	"386":      "GO386",
+	"amd64":    "GOAMD64",
+	"arm":      "GOARM",
+	"arm64":    "GOARM64",
+	"mips":     "GOMIPS",
+	"mipsle":   "GOMIPS",
+	"mips64":   "GOMIPS64",
+	"mips64le": "GOMIPS64",
+	"ppc64":    "GOPPC64",
+	"ppc64le":  "GOPPC64",
+	"riscv64":  "GORISCV64",
+	"wasm":     "GOWASM",
+}
+
+// VariantVar returns the environment variable selecting microarchitecture
+// variants of an architecture, like GOAMD64 for amd64, or an empty string
+// if the architecture has no variants.
+func VariantVar(arch string) string {
+	return variantVars[arch]
+}
+
+// ParseTarget parses a target in `os_arch` or `os_arch_variant` format,
+// like linux_amd64_v3, linux_arm_7, or darwin_arm64.
+func ParseTarget(target string) (*OsArch, error) {
+	items := strings.SplitN(target, "_", 3)
+	if len(items) < 2 || items[0] == "" || items[1] == "" {
+		return nil, fmt.Errorf("invalid target %q, expecting os_arch, or os_arch_variant", target)
+	}
+
+	oa := &OsArch{OS: items[0], Arch: items[1]}
+
+	if len(items) < 3 {
+		return oa, nil
+	}
+
+	if VariantVar(oa.Arch) == "" {
+		return nil, fmt.Errorf("invalid target %q: architecture %s has no variants", target, oa.Arch)
+	}
+
+	if _, err := strconv.ParseInt(items[2], 10, 32); oa.Arch == "arm" && err != nil {
+		return nil, fmt.Errorf("invalid target %q: invalid ARM version %q", target, items[2])
+	}
+
+	oa.Variant = items[2]
+
+	return oa, nil
+}
+
+// Target returns the OS-arch in target format, like linux_amd64_v3
+func (oa *OsArch) Target() string {
+	if oa.Variant != "" {
+		return fmt.Sprintf("%s_%s_%s", oa.OS, oa.Arch, oa.Variant)
+	}
+
+	return fmt.Sprintf("%s_%s", oa.OS, oa.Arch)
+}
+
+// ArchName returns the architecture with its variant, like armv7,
+// amd64v3, or mips_softfloat. ARM versions are appended with a v prefix,
+// variants starting with a version number are appended as they are, and
+// others are separated by an underscore. Variants of architectures
+// without variant variables are ignored.
 func (oa *OsArch) ArchName() string {
-	if oa.Arch == "arm" && oa.ArmVersion > 0 {
-		return fmt.Sprintf("%sv%d", oa.Arch, oa.ArmVersion)
+	if oa.Variant == "" || VariantVar(oa.Arch) == "" {
+		return oa.Arch
+	}
+
+	variant := strings.ReplaceAll(oa.Variant, ",", "_")
+
+	switch {
+	case isDigit(variant[0]):
+		return oa.Arch + "v" + variant
+	case len(variant) > 1 && variant[0] == 'v' && isDigit(variant[1]):
+		return oa.Arch + variant
 	}
 
-	return oa.Arch
+	return oa.Arch + "_" + variant
 }
 
 func (oa *OsArch) String() string {
@@ -23,3 +103,7 @@ func (oa *OsArch) String() string {
 
 	return fmt.Sprintf("%s-%s", oa.OS, oa.ArchName())
 }
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -19,12 +19,12 @@ func TestOsarch_ArchName(t *testing.T) {
 		},
 		{
 			name:     "fake arm version",
-			oa:       ctx.OsArch{OS: "conventional", Arch: "architecture", ArmVersion: 2},
+			oa:       ctx.OsArch{OS: "conventional", Arch: "architecture", Variant: "2"},
 			archname: "architecture",
 		},
 		{
 			name:     "real arm version",
-			oa:       ctx.OsArch{OS: "conventional", Arch: "arm", ArmVersion: 2},
+			oa:       ctx.OsArch{OS: "conventional", Arch: "arm", Variant: "2"},
 			archname: "armv2",
 		},
 	}
@@ -54,12 +54,12 @@ func TestOsarch_String(t *testing.T) {
 		},
 		{
 			name:     "fake arm version",
-			oa:       &ctx.OsArch{OS: "conventional", Arch: "architecture", ArmVersion: 2},
+			oa:       &ctx.OsArch{OS: "conventional", Arch: "architecture", Variant: "2"},
 			archname: "conventional-architecture",
 		},
 		{
 			name:     "real arm version",
-			oa:       &ctx.OsArch{OS: "conventional", Arch: "arm", ArmVersion: 2},
+			oa:       &ctx.OsArch{OS: "conventional", Arch: "arm", Variant: "2"},
 			archname: "conventional-armv2",
 		},
 		{
@@ -80,3 +80,46 @@ func TestOsarch_String(t *testing.T) {
 		})
 	}
 }
+
+func TestOsarch_variants(t *testing.T) {
+	tests := []struct {
+		target   string
+		oa       ctx.OsArch
+		archname string
+	}{
+		{"darwin_arm64", ctx.OsArch{OS: "darwin", Arch: "arm64"}, "arm64"},
+		{"linux_amd64_v3", ctx.OsArch{OS: "linux", Arch: "amd64", Variant: "v3"}, "amd64v3"},
+		{"linux_arm_7", ctx.OsArch{OS: "linux", Arch: "arm", Variant: "7"}, "armv7"},
+		{"linux_arm64_v8.1,lse", ctx.OsArch{OS: "linux", Arch: "arm64", Variant: "v8.1,lse"}, "arm64v8.1_lse"},
+		{"linux_mipsle_softfloat", ctx.OsArch{OS: "linux", Arch: "mipsle", Variant: "softfloat"}, "mipsle_softfloat"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.target, func(t *testing.T) {
+			oa, err := ctx.ParseTarget(tt.target)
+			if err != nil {
+				t.Fatalf("ParseTarget() unexpected error: %v", err)
+			}
+
+			if *oa != tt.oa {
+				t.Errorf("ParseTarget() = %+v, wants %+v", *oa, tt.oa)
+			}
+
+			if archname := oa.ArchName(); archname != tt.archname {
+				t.Errorf("ArchName returned %q, wants %q", archname, tt.archname)
+			}
+
+			if target := oa.Target(); target != tt.target {
+				t.Errorf("Target returned %q, wants %q", target, tt.target)
+			}
+		})
+	}
+
+	for _, target := range []string{"linux", "linux__v3", "linux_s390x_z15", "linux_arm_v7"} {
+		if _, err := ctx.ParseTarget(target); err == nil {
+			t.Errorf("ParseTarget(%q) unexpected success", target)
+		}
+	}
+}
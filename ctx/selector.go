@@ -0,0 +1,208 @@
+package ctx
+
+import (
+	"path"
+	"strconv"
+)
+
+type (
+	// ArtifactFilter matches artifacts by their attributes. Empty fields
+	// match all artifacts. ID, OsArch, and Filename are glob patterns, in
+	// path.Match format. OsArch matches OS-arch names, like linux-amd64,
+	// linux-armv7, or noarch. Arch matches both architectures, and
+	// architecture names, like arm, and armv7.
+	ArtifactFilter struct {
+		ID         string       `yaml:"id,omitempty" json:"id,omitempty"`
+		OS         string       `yaml:"os,omitempty" json:"os,omitempty"`
+		Arch       string       `yaml:"arch,omitempty" json:"arch,omitempty"`
+		ArmVersion int32        `yaml:"goarm,omitempty" json:"goarm,omitempty"`
+		OsArch     string       `yaml:"osarch,omitempty" json:"osarch,omitempty"`
+		Kind       ArtifactKind `yaml:"kind,omitempty" json:"kind,omitempty"`
+		Filename   string       `yaml:"filename,omitempty" json:"filename,omitempty"`
+	}
+
+	// Selector selects artifacts matching any of its Include filters, and
+	// none of its Exclude filters.
+	Selector struct {
+		Include []*ArtifactFilter `yaml:"include,omitempty" json:"include,omitempty"`
+		Exclude []*ArtifactFilter `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	}
+)
+
+// IDSelector returns a selector of artifacts by their IDs
+func IDSelector(ids ...string) *Selector {
+	sel := &Selector{Include: make([]*ArtifactFilter, 0, len(ids))}
+
+	for _, id := range ids {
+		sel.Include = append(sel.Include, &ArtifactFilter{ID: id})
+	}
+
+	return sel
+}
+
+// Match checks whether an artifact matches all fields of the filter
+func (filter *ArtifactFilter) Match(art *Artifact) bool {
+	if !matchPattern(filter.ID, art.ID) ||
+		!matchPattern(filter.Filename, art.Filename) ||
+		!matchPattern(filter.OsArch, art.OsArch.String()) {
+		return false
+	}
+
+	if filter.Kind != KindUnknown && filter.Kind != art.Kind {
+		return false
+	}
+
+	if filter.OS == "" && filter.Arch == "" && filter.ArmVersion == 0 {
+		return true
+	}
+
+	if art.OsArch == nil {
+		return false
+	}
+
+	if filter.ArmVersion != 0 && (art.OsArch.Arch != "arm" || art.OsArch.Variant != strconv.Itoa(int(filter.ArmVersion))) {
+		return false
+	}
+
+	return matchPattern(filter.OS, art.OsArch.OS) &&
+		(matchPattern(filter.Arch, art.OsArch.Arch) || matchPattern(filter.Arch, art.OsArch.ArchName()))
+}
+
+// IsIDOnly tells whether the filter matches artifact IDs only
+func (filter *ArtifactFilter) IsIDOnly() bool {
+	return filter.ID != "" && *filter == ArtifactFilter{ID: filter.ID}
+}
+
+// Patterns returns all glob patterns of the filter
+func (filter *ArtifactFilter) Patterns() []string {
+	patterns := []string{}
+
+	for _, pattern := range []string{filter.ID, filter.OS, filter.Arch, filter.OsArch, filter.Filename} {
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// Match checks whether an artifact is selected
+func (sel *Selector) Match(art *Artifact) bool {
+	if sel == nil {
+		return false
+	}
+
+	for _, filter := range sel.Exclude {
+		if filter.Match(art) {
+			return false
+		}
+	}
+
+	for _, filter := range sel.Include {
+		if filter.Match(art) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IDs returns artifact ID patterns the selector can select from. Include
+// filters without IDs match all artifact IDs, which is returned as "*".
+func (sel *Selector) IDs() []string {
+	if sel == nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(sel.Include))
+	seen := map[string]bool{}
+
+	for _, filter := range sel.Include {
+		id := filter.ID
+		if id == "" {
+			id = "*"
+		}
+
+		if !seen[id] {
+			seen[id] = true
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Without returns a copy of the selector, excluding artifacts matching any
+// of the filters too
+func (sel *Selector) Without(filters ...*ArtifactFilter) *Selector {
+	ret := &Selector{}
+
+	if sel != nil {
+		ret.Include = append(ret.Include, sel.Include...)
+		ret.Exclude = append(ret.Exclude, sel.Exclude...)
+	}
+
+	ret.Exclude = append(ret.Exclude, filters...)
+
+	return ret
+}
+
+// MatchID checks whether an artifact ID matches an ID pattern, like the
+// ones returned by Selector.IDs. Invalid patterns match themselves only.
+func MatchID(pattern, id string) bool {
+	return matchPattern(pattern, id)
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" || pattern == value {
+		return true
+	}
+
+	ok, err := path.Match(pattern, value)
+
+	return ok && err == nil
+}
+
+// Select returns artifacts selected by a selector, in the order of the
+// selector's Include filters, and the order they were added
+func (arts *Artifacts) Select(sel *Selector) *Artifacts {
+	results := &Artifacts{}
+
+	if sel == nil {
+		return results
+	}
+
+	added := map[*Artifact]bool{}
+
+	for _, filter := range sel.Include {
+		for _, art := range *arts {
+			if added[art] || !filter.Match(art) || !sel.Match(art) {
+				continue
+			}
+
+			added[art] = true
+
+			*results = append(*results, art)
+		}
+	}
+
+	return results
+}
+
+// OsArchBySelector maps artifacts by OS-Arch, filtering by a selector
+func (arts *Artifacts) OsArchBySelector(sel *Selector) map[string]*Artifacts {
+	builds := map[string]*Artifacts{}
+
+	for _, art := range *arts.Select(sel) {
+		osarch := art.OsArch.String()
+
+		if _, ok := builds[osarch]; !ok {
+			builds[osarch] = &Artifacts{}
+		}
+
+		*builds[osarch] = append(*builds[osarch], art)
+	}
+
+	return builds
+}
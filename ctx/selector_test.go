@@ -0,0 +1,95 @@
+package ctx
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestArtifacts_Select(t *testing.T) {
+	arts := Artifacts{
+		&Artifact{ID: "default", Filename: "app", Kind: KindBinary, OsArch: &OsArch{OS: "linux", Arch: "amd64"}},
+		&Artifact{ID: "default", Filename: "app", Kind: KindBinary, OsArch: &OsArch{OS: "linux", Arch: "arm", Variant: "7"}},
+		&Artifact{ID: "default", Filename: "app.exe", Kind: KindBinary, OsArch: &OsArch{OS: "windows", Arch: "amd64"}},
+		&Artifact{ID: "archive", Filename: "app-linux-amd64.tar.gz", Kind: KindArchive, OsArch: &OsArch{OS: "linux", Arch: "amd64"}},
+		&Artifact{ID: "archive", Filename: "app-windows-amd64.tar.gz", Kind: KindArchive, OsArch: &OsArch{OS: "windows", Arch: "amd64"}},
+		&Artifact{ID: "checksum", Filename: "checksums.txt", Kind: KindChecksum},
+	}
+
+	tests := []struct {
+		name string
+		sel  *Selector
+		want []string
+	}{
+		{
+			name: "by IDs",
+			sel:  IDSelector("checksum", "archive"),
+			want: []string{"checksums.txt", "app-linux-amd64.tar.gz", "app-windows-amd64.tar.gz"},
+		},
+		{
+			name: "ID glob",
+			sel:  IDSelector("*"),
+			want: []string{"app", "app", "app.exe", "app-linux-amd64.tar.gz", "app-windows-amd64.tar.gz", "checksums.txt"},
+		},
+		{
+			name: "archives for linux",
+			sel:  &Selector{Include: []*ArtifactFilter{{Kind: KindArchive, OS: "linux"}}},
+			want: []string{"app-linux-amd64.tar.gz"},
+		},
+		{
+			name: "everything except windows",
+			sel: &Selector{
+				Include: []*ArtifactFilter{{}},
+				Exclude: []*ArtifactFilter{{OS: "windows"}},
+			},
+			want: []string{"app", "app", "app-linux-amd64.tar.gz", "checksums.txt"},
+		},
+		{
+			name: "arch name",
+			sel:  &Selector{Include: []*ArtifactFilter{{Arch: "armv7"}}},
+			want: []string{"app"},
+		},
+		{
+			name: "ARM version",
+			sel:  &Selector{Include: []*ArtifactFilter{{Arch: "arm", ArmVersion: 6}}},
+			want: []string{},
+		},
+		{
+			name: "file name pattern",
+			sel:  &Selector{Include: []*ArtifactFilter{{Filename: "*.tar.gz"}}},
+			want: []string{"app-linux-amd64.tar.gz", "app-windows-amd64.tar.gz"},
+		},
+		{
+			name: "skipping OS-arch names",
+			sel:  IDSelector("default", "checksum").Without(&ArtifactFilter{OsArch: "linux-*"}, &ArtifactFilter{OsArch: "noarch"}),
+			want: []string{"app.exe"},
+		},
+		{
+			name: "nil",
+			sel:  nil,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, art := range *arts.Select(tt.sel) {
+				got = append(got, art.Filename)
+			}
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Errorf("Artifacts.Select() %v", diff)
+			}
+		})
+	}
+}
+
+func TestSelector_IDs(t *testing.T) {
+	sel := &Selector{Include: []*ArtifactFilter{{ID: "default"}, {Kind: KindArchive}, {ID: "default", OS: "linux"}}}
+
+	if diff := deep.Equal(sel.IDs(), []string{"default", "*"}); diff != nil {
+		t.Errorf("Selector.IDs() %v", diff)
+	}
+}
@@ -7,8 +7,9 @@ require (
 	github.com/go-test/deep v1.0.8
 	github.com/google/go-github/v28 v28.1.1
 	github.com/julian7/withenv v0.2.0
-	github.com/magefile/mage v1.12.1
+	github.com/klauspost/compress v1.15.15
 	github.com/spf13/afero v1.8.1
+	github.com/ulikunitz/xz v0.5.11
 	github.com/xanzy/go-gitlab v0.55.1
 	golang.org/x/oauth2 v0.0.0-20220223155221-ee480838109b
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
@@ -24,6 +25,7 @@ require (
 	github.com/hashicorp/go-retryablehttp v0.7.0 // indirect
 	github.com/julian7/sensulib v0.4.0 // indirect
 	github.com/kr/pretty v0.2.0 // indirect
+	github.com/magefile/mage v1.12.1 // indirect
 	github.com/mattn/go-colorable v0.1.12 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
 	golang.org/x/crypto v0.0.0-20220214200702-86341886e292 // indirect
@@ -3,8 +3,12 @@
 package goshipdone
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/julian7/goshipdone/pipeline"
 	"github.com/spf13/afero"
@@ -23,42 +27,157 @@ var defaultFS = afero.NewOsFs()
 // It tries to load files from the following sources:
 // - provided filename
 // - GOSHIPDONE_CONFIG environment variable
-// - .goshipdone.local.yml (if exists)
-// - .goshipdone.yml
+// - .goshipdone.yml, with .goshipdone.local.yml merged on top (if exists)
 //
 // It returns an error if any of the subsequent processing has an error.
+// SIGINT and SIGTERM interrupt the run, see SignalContext.
 func Run(filename string) error {
-	filename = detectFilename(filename)
+	cx, stop := SignalContext()
+	defer stop()
 
-	content, err := afero.ReadFile(defaultFS, filename)
+	return RunContext(cx, filename)
+}
+
+// RunContext executes all steps in the pipeline like Run, but it is
+// interrupted by cancelling cx: running commands are killed, partially
+// written files are removed, and the error reports the module
+// interrupted.
+func RunContext(cx context.Context, filename string) error {
+	pipe, err := Load(filename)
 	if err != nil {
-		return fmt.Errorf("loading GoShipDone file: %w", err)
+		return err
+	}
+
+	if err := pipe.Run(cx); err != nil {
+		return fmt.Errorf("running GoShipDone: %w", err)
 	}
 
-	pipe, err := pipeline.LoadBuildPipeline(content)
+	return nil
+}
+
+// Publish runs the publish stage of the pipeline alone, starting from a
+// manifest saved by an earlier run (see Pipeline.RunFromManifest). It
+// allows building and publishing in separate processes, eg. in separate
+// CI jobs. Manifest defaults to metadata.json in the target directory.
+// Configuration is loaded the same way as in Run, and stages between the
+// first one and publish are skipped.
+func Publish(filename, manifest string) error {
+	cx, stop := SignalContext()
+	defer stop()
+
+	pipe, err := Load(filename)
 	if err != nil {
-		return fmt.Errorf("processing GoShipDone file: %w", err)
+		return err
 	}
 
-	if err := pipe.Run(); err != nil {
-		return fmt.Errorf("running GoShipDone: %w", err)
+	if err := pipe.StartAt("publish"); err != nil {
+		return err
+	}
+
+	if err := pipe.RunFromManifest(cx, manifest); err != nil {
+		return fmt.Errorf("publishing with GoShipDone: %w", err)
 	}
 
 	return nil
 }
 
-func detectFilename(filename string) string {
+// SignalContext returns a context, which is cancelled on SIGINT or
+// SIGTERM. After the first signal, signals are handled the default way
+// again, therefore a second one terminates the process immediately.
+func SignalContext() (context.Context, context.CancelFunc) {
+	cx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-cx.Done()
+		stop()
+	}()
+
+	return cx, stop
+}
+
+// Plan describes what Run would do, without actually running anything. It
+// loads configuration the same way Run does, and writes the plan into w,
+// in the specified format ("text" or "json").
+func Plan(filename string, w io.Writer, format string) error {
+	pipe, err := Load(filename)
+	if err != nil {
+		return err
+	}
+
+	plan, err := pipe.Plan()
+	if err != nil {
+		return fmt.Errorf("planning GoShipDone: %w", err)
+	}
+
+	return plan.Write(w, format)
+}
+
+// Schema writes a JSON Schema of the configuration file into w. It covers
+// all registered modules, including custom ones registered before calling
+// Schema.
+func Schema(w io.Writer) error {
+	schema := pipeline.BuildSchema()
+	schema.Properties[includeKey] = &pipeline.Schema{
+		Description: "files to be merged into the configuration, before this file",
+		OneOf: []*pipeline.Schema{
+			{Type: "string"},
+			{Type: "array", Items: &pipeline.Schema{Type: "string"}},
+		},
+	}
+	schema.Properties[profilesKey] = &pipeline.Schema{
+		Description:          "named overlays, merged on top of the configuration when selected",
+		Type:                 "object",
+		AdditionalProperties: &pipeline.Schema{Type: "object"},
+	}
+
+	return schema.Write(w)
+}
+
+// Load loads the pipeline, defined by YAML configuration file. It finds
+// the configuration file the same way Run does. Configuration files can
+// include other files, and selected profiles are merged on top of the
+// configuration.
+func Load(filename string, profiles ...string) (*pipeline.Pipeline, error) {
+	comp := newComposer(defaultFS)
+
+	node, err := comp.compose(detectFilenames(filename), profiles)
+	if err != nil {
+		return nil, fmt.Errorf("processing GoShipDone file: %w", err)
+	}
+
+	pipe, err := pipeline.LoadBuildPipelineNode(node)
+	if err != nil {
+		return nil, fmt.Errorf("processing GoShipDone file: %w", comp.translate(err))
+	}
+
+	return pipe, nil
+}
+
+// detectFilenames returns the configuration file, and its local overlay
+// if exists. The overlay is used only if the configuration file is not
+// specified explicitly.
+func detectFilenames(filename string) []string {
 	if filename != "" {
-		return filename
+		return []string{filename}
 	}
 
 	if fn, ok := os.LookupEnv(filenameEnv); ok {
-		return fn
+		return []string{fn}
 	}
 
-	if st, err := defaultFS.Stat(defaultLocalFilename); err == nil && st.Mode().IsRegular() {
-		return defaultLocalFilename
+	if !isFile(defaultLocalFilename) {
+		return []string{defaultFilename}
 	}
 
-	return defaultFilename
+	if !isFile(defaultFilename) {
+		return []string{defaultLocalFilename}
+	}
+
+	return []string{defaultFilename, defaultLocalFilename}
+}
+
+func isFile(filename string) bool {
+	st, err := defaultFS.Stat(filename)
+
+	return err == nil && st.Mode().IsRegular()
 }
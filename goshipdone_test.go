@@ -4,35 +4,43 @@ package goshipdone
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
 )
 
-func Test_detectFilename(t *testing.T) {
+func Test_detectFilenames(t *testing.T) {
 	tests := []struct {
 		name  string
 		env   string
 		input string
 		files []string
-		want  string
+		want  []string
 	}{
-		{name: "only env", env: "a", input: "", want: "a"},
-		{name: "only input", env: "", input: "b", want: "b"},
-		{name: "both env and input", env: "a", input: "b", want: "b"},
+		{name: "only env", env: "a", input: "", want: []string{"a"}},
+		{name: "only input", env: "", input: "b", want: []string{"b"}},
+		{name: "both env and input", env: "a", input: "b", want: []string{"b"}},
 		{
 			name:  "local file exists",
 			env:   "",
 			input: "",
 			files: []string{".goshipdone.local.yml"},
-			want:  ".goshipdone.local.yml",
+			want:  []string{".goshipdone.local.yml"},
+		},
+		{
+			name:  "local file overlays",
+			env:   "",
+			input: "",
+			files: []string{".goshipdone.yml", ".goshipdone.local.yml"},
+			want:  []string{".goshipdone.yml", ".goshipdone.local.yml"},
 		},
 		{
 			name:  "local file doesn't exists",
 			env:   "",
 			input: "",
 			files: []string{},
-			want:  ".goshipdone.yml",
+			want:  []string{".goshipdone.yml"},
 		},
 	}
 	for _, tt := range tests {
@@ -47,8 +55,8 @@ func Test_detectFilename(t *testing.T) {
 				_ = afero.WriteFile(defaultFS, filename, []byte(filename), 0o644)
 			}
 
-			if got := detectFilename(tt.input); got != tt.want {
-				t.Errorf("detectFilename() = %q, want %q", got, tt.want)
+			if got := detectFilenames(tt.input); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("detectFilenames() = %q, want %q", got, tt.want)
 			}
 			defaultFS = origFS
 			if tt.env != "" {
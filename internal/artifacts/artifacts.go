@@ -10,11 +10,13 @@ import (
 	"strings"
 
 	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
 type (
 	Service interface {
+		Name() string
 		DefaultTokenEnv() string
 		DefaultTokenFile() string
 		New(ctx context.Context, url, token, owner, name string, opts *tls.Config) (Connection, error)
@@ -75,17 +77,17 @@ func (s *Storage) GetToken(cx context.Context, tokenEnv, tokenFile string) strin
 
 func (s *Storage) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.ScalarNode {
-		return fmt.Errorf("storage is `%v`, not scalar", node.Kind)
+		return modules.NewConfigError(node, fmt.Errorf("storage is `%v`, not scalar", node.Kind))
 	}
 
 	var storageName string
 	if err := node.Decode(&storageName); err != nil {
-		return fmt.Errorf("storage cannot be decoded: %w", err)
+		return modules.NewConfigError(node, fmt.Errorf("storage cannot be decoded: %w", err))
 	}
 
 	service, err := s.Load(storageName)
 	if err != nil {
-		return err
+		return modules.NewConfigError(node, err)
 	}
 
 	s.Service = service
@@ -93,6 +95,16 @@ func (s *Storage) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+// MarshalYAML returns the storage service's name
+func (s Storage) MarshalYAML() (interface{}, error) {
+	return s.Service.Name(), nil
+}
+
+// Enum returns valid storage names
+func (Storage) Enum() []string {
+	return []string{"github", "GitHub", "Github", "gitlab", "GitLab", "Gitlab"}
+}
+
 func (s *Storage) Load(name string) (Service, error) {
 	switch name {
 	case "", "github", "GitHub", "Github":
@@ -5,10 +5,11 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 
 	"github.com/blang/semver"
 	"github.com/google/go-github/v28/github"
@@ -26,6 +27,10 @@ type GitHubRelease struct {
 	Ver  string
 }
 
+func (*GitHubService) Name() string {
+	return "github"
+}
+
 func (*GitHubService) DefaultTokenEnv() string {
 	return "GITHUB_TOKEN"
 }
@@ -102,24 +107,25 @@ func (c *GitHubClient) setURLs(baseURL string) error {
 func (rel *GitHubRelease) Release(name, notes string) error {
 	data := rel.getReleaseData(name, notes)
 
-	release, _, err := rel.Conn.Client.Repositories.GetReleaseByTag(
+	release, resp, err := rel.Conn.Client.Repositories.GetReleaseByTag(
 		rel.Conn.Context,
 		rel.Conn.Owner,
 		rel.Conn.Name,
 		data.GetTagName(),
 	)
 	if err != nil {
-		var resp *github.Response
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return retryable(fmt.Errorf("searching existing release %s: %w", data.GetTagName(), err))
+		}
 
-		release, resp, err = rel.Conn.Client.Repositories.CreateRelease(
+		release, _, err = rel.Conn.Client.Repositories.CreateRelease(
 			rel.Conn.Context,
 			rel.Conn.Owner,
 			rel.Conn.Name,
 			data,
 		)
 		if err != nil {
-			returned, _ := ioutil.ReadAll(resp.Response.Body)
-			return fmt.Errorf("creating release %s: %w (%s)", rel.Ver, err, string(returned))
+			return retryable(fmt.Errorf("creating release %s: %w", rel.Ver, err))
 		}
 	} else {
 		relID := release.GetID()
@@ -135,7 +141,7 @@ func (rel *GitHubRelease) Release(name, notes string) error {
 			data,
 		)
 		if err != nil {
-			return fmt.Errorf("editing release %d: %w", relID, err)
+			return retryable(fmt.Errorf("editing release %d: %w", relID, err))
 		}
 	}
 
@@ -165,32 +171,98 @@ func (rel *GitHubRelease) getReleaseData(name, notes string) *github.RepositoryR
 	}
 }
 
+// Upload uploads an artifact into the release. An asset of the same name
+// is replaced, allowing retried uploads to succeed.
 func (rel *GitHubRelease) Upload(art *ctx.Artifact) error {
 	if rel.ID == 0 {
 		return errors.New("no release selected")
 	}
 
+	if err := rel.removeAsset(art.Filename); err != nil {
+		return err
+	}
+
 	file, err := os.Open(art.Location)
 	if err != nil {
 		return fmt.Errorf("opening file %s for uploading: %w", art.Location, err)
 	}
 
+	defer file.Close()
+
 	if _, _, err = rel.Conn.Client.Repositories.UploadReleaseAsset(
 		rel.Conn.Context,
 		rel.Conn.Owner,
 		rel.Conn.Name,
 		rel.ID,
 		&github.UploadOptions{
-			Name: art.Filename,
+			Name:      art.Filename,
+			MediaType: mediaType(art),
 		},
 		file,
 	); err != nil {
-		return fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err)
+		return retryable(fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err))
 	}
 
 	return nil
 }
 
+// removeAsset removes the release's assets of a name, if any, including
+// partial uploads of earlier attempts
+func (rel *GitHubRelease) removeAsset(name string) error {
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		assets, resp, err := rel.Conn.Client.Repositories.ListReleaseAssets(
+			rel.Conn.Context,
+			rel.Conn.Owner,
+			rel.Conn.Name,
+			rel.ID,
+			opts,
+		)
+		if err != nil {
+			return retryable(fmt.Errorf("listing assets of %v: %w", rel, err))
+		}
+
+		for _, asset := range assets {
+			if asset.GetName() != name {
+				continue
+			}
+
+			if _, err := rel.Conn.Client.Repositories.DeleteReleaseAsset(
+				rel.Conn.Context,
+				rel.Conn.Owner,
+				rel.Conn.Name,
+				asset.GetID(),
+			); err != nil {
+				return retryable(fmt.Errorf("removing asset %s from %v: %w", name, rel, err))
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// mediaType returns the media type of an artifact by its kind, or an
+// empty string, to detect it by the file's extension
+func mediaType(art *ctx.Artifact) string {
+	switch art.Kind {
+	case ctx.KindBinary:
+		return "application/octet-stream"
+	case ctx.KindChecksum, ctx.KindReleaseNotes:
+		return "text/plain"
+	}
+
+	if mime.TypeByExtension(path.Ext(art.Filename)) == "" {
+		return "application/octet-stream"
+	}
+
+	return ""
+}
+
 func (rel *GitHubRelease) String() string {
 	return fmt.Sprintf("%s/%s #%d", rel.Conn.Owner, rel.Conn.Name, rel.ID)
 }
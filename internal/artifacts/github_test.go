@@ -0,0 +1,65 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestGitHubRelease_Upload(t *testing.T) {
+	var (
+		calls []string
+		lock  sync.Mutex
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		calls = append(calls, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `[{"id": 5, "name": "dist.tar.gz"}, {"id": 6, "name": "other.tar.gz"}]`)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 7, "name": "dist.tar.gz"}`)
+		}
+	}))
+	defer server.Close()
+
+	location := path.Join(t.TempDir(), "dist.tar.gz")
+	if err := os.WriteFile(location, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := (&GitHubService{}).New(context.Background(), server.URL+"/", "token", "owner", "name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel := &GitHubRelease{Conn: conn.(*GitHubClient), ID: 1}
+
+	if err := rel.Upload(&ctx.Artifact{Filename: "dist.tar.gz", Location: location}); err != nil {
+		t.Fatalf("GitHubRelease.Upload() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"GET /api/v3/repos/owner/name/releases/1/assets",
+		"DELETE /api/v3/repos/owner/name/releases/assets/5",
+		"POST /api/uploads/repos/owner/name/releases/1/assets",
+	}
+
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Errorf("GitHubRelease.Upload() calls %v", diff)
+	}
+}
@@ -36,6 +36,10 @@ type GitLabRelease struct {
 	Ver  string
 }
 
+func (*GitLabService) Name() string {
+	return "gitlab"
+}
+
 func (*GitLabService) DefaultTokenEnv() string {
 	return "GITLAB_TOKEN"
 }
@@ -49,7 +53,8 @@ func (*GitLabService) New(
 	url, token, namespace, name string,
 	options *tls.Config,
 ) (Connection, error) {
-	opts := []gitlab.ClientOptionFunc{}
+	// retries are handled by the publish module's retry policy
+	opts := []gitlab.ClientOptionFunc{gitlab.WithoutRetries()}
 
 	if url != "" {
 		opts = append(opts, gitlab.WithBaseURL(url))
@@ -76,9 +81,9 @@ func (*GitLabService) New(
 }
 
 func (c *GitLabClient) NewReleaser(tag, ref, version string) (Releaser, error) {
-	proj, _, err := c.Projects.GetProject(c.ProjectPath(), nil)
+	proj, _, err := c.Projects.GetProject(c.ProjectPath(), nil, gitlab.WithContext(c.Context))
 	if err != nil {
-		return nil, fmt.Errorf("getting project info: %w", err)
+		return nil, retryable(fmt.Errorf("getting project info: %w", err))
 	}
 
 	return &GitLabRelease{
@@ -101,17 +106,17 @@ func (c *GitLabClient) ProjectID() string {
 func (rel *GitLabRelease) Release(name, notes string) error {
 	var release *gitlab.Release
 
-	tag := rel.Tag
-	if tag == "" {
-		tag = rel.Ver
-	}
-
+	tag := rel.tagName()
 	projectPath := rel.Conn.ProjectPath()
 
-	_, resp, err := rel.Conn.Client.Releases.GetRelease(rel.Conn.ProjectID(), tag)
+	_, resp, err := rel.Conn.Client.Releases.GetRelease(
+		rel.Conn.ProjectID(),
+		tag,
+		gitlab.WithContext(rel.Conn.Context),
+	)
 	if err != nil {
-		if resp.StatusCode != 404 {
-			return fmt.Errorf("searching existing release %s: %w", tag, err)
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return retryable(fmt.Errorf("searching existing release %s: %w", tag, err))
 		}
 
 		release, _, err = rel.Conn.Client.Releases.CreateRelease(
@@ -122,10 +127,10 @@ func (rel *GitLabRelease) Release(name, notes string) error {
 				Ref:         &rel.Ref,
 				TagName:     &tag,
 			},
+			gitlab.WithContext(rel.Conn.Context),
 		)
 		if err != nil {
-			returned, _ := ioutil.ReadAll(resp.Response.Body)
-			return fmt.Errorf("creating release %s: %w (%s)", rel.Ver, err, string(returned))
+			return retryable(fmt.Errorf("creating release %s: %w", rel.Ver, err))
 		}
 	} else {
 		release, _, err = rel.Conn.Client.Releases.UpdateRelease(
@@ -135,9 +140,10 @@ func (rel *GitLabRelease) Release(name, notes string) error {
 				Name:        &name,
 				Description: &notes,
 			},
+			gitlab.WithContext(rel.Conn.Context),
 		)
 		if err != nil {
-			return fmt.Errorf("editing release %s: %w", tag, err)
+			return retryable(fmt.Errorf("editing release %s: %w", tag, err))
 		}
 	}
 
@@ -146,6 +152,16 @@ func (rel *GitLabRelease) Release(name, notes string) error {
 	return nil
 }
 
+// tagName returns the git tag of the release, or its version if the
+// commit is not tagged
+func (rel *GitLabRelease) tagName() string {
+	if rel.Tag == "" {
+		return rel.Ver
+	}
+
+	return rel.Tag
+}
+
 func (rel *GitLabRelease) uploadFile(filename, location string) (*gitlab.ProjectFile, error) {
 	file, err := os.Open(location)
 	if err != nil {
@@ -170,7 +186,7 @@ func (rel *GitLabRelease) uploadFile(filename, location string) (*gitlab.Project
 
 	_ = w.Close()
 
-	req, err := rel.Conn.NewRequest("", u, nil, nil)
+	req, err := rel.Conn.NewRequest("", u, nil, []gitlab.RequestOptionFunc{gitlab.WithContext(rel.Conn.Context)})
 	if err != nil {
 		return nil, fmt.Errorf("setting up new request to upload %s: %w", filename, err)
 	}
@@ -183,12 +199,14 @@ func (rel *GitLabRelease) uploadFile(filename, location string) (*gitlab.Project
 
 	_, err = rel.Conn.Do(req, projFile)
 	if err != nil {
-		return nil, fmt.Errorf("uploading file %s: %w", filename, err)
+		return nil, retryable(fmt.Errorf("uploading file %s: %w", filename, err))
 	}
 
 	return projFile, nil
 }
 
+// Upload uploads an artifact, and links it to the release. A link of the
+// same name is replaced, allowing retried uploads to succeed.
 func (rel *GitLabRelease) Upload(art *ctx.Artifact) error {
 	if rel.ID == "" {
 		return errors.New("no release selected")
@@ -202,25 +220,79 @@ func (rel *GitLabRelease) Upload(art *ctx.Artifact) error {
 		return err
 	}
 
+	if err := rel.removeLink(art.Filename); err != nil {
+		return err
+	}
+
 	fileURL := rel.Base + projectFile.URL
 
-	relLink, _, err := rel.Conn.ReleaseLinks.CreateReleaseLink(
+	_, _, err = rel.Conn.ReleaseLinks.CreateReleaseLink(
 		rel.Conn.ProjectPath(),
-		rel.ID,
+		rel.tagName(),
 		&gitlab.CreateReleaseLinkOptions{
-			Name: &art.Filename,
-			URL:  &fileURL,
+			Name:     &art.Filename,
+			URL:      &fileURL,
+			LinkType: gitlab.LinkType(linkType(art)),
 		},
+		gitlab.WithContext(rel.Conn.Context),
 	)
 	if err != nil {
-		return fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err)
+		return retryable(fmt.Errorf("uploading file %s into %v: %w", art.Location, rel, err))
 	}
 
-	_ = relLink
-
 	return nil
 }
 
+// removeLink removes the release's links of a name, if any
+func (rel *GitLabRelease) removeLink(name string) error {
+	opts := &gitlab.ListReleaseLinksOptions{PerPage: 100}
+
+	for {
+		links, resp, err := rel.Conn.ReleaseLinks.ListReleaseLinks(
+			rel.Conn.ProjectPath(),
+			rel.tagName(),
+			opts,
+			gitlab.WithContext(rel.Conn.Context),
+		)
+		if err != nil {
+			return retryable(fmt.Errorf("listing links of %v: %w", rel, err))
+		}
+
+		for _, link := range links {
+			if link.Name != name {
+				continue
+			}
+
+			if _, _, err := rel.Conn.ReleaseLinks.DeleteReleaseLink(
+				rel.Conn.ProjectPath(),
+				rel.tagName(),
+				link.ID,
+				gitlab.WithContext(rel.Conn.Context),
+			); err != nil {
+				return retryable(fmt.Errorf("removing link %s from %v: %w", name, rel, err))
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// linkType returns the release link type of an artifact by its kind
+func linkType(art *ctx.Artifact) gitlab.LinkTypeValue {
+	switch art.Kind {
+	case ctx.KindImage:
+		return gitlab.ImageLinkType
+	case ctx.KindPackage:
+		return gitlab.PackageLinkType
+	}
+
+	return gitlab.OtherLinkType
+}
+
 func (rel *GitLabRelease) String() string {
 	return fmt.Sprintf("%s/%s release %s", rel.Conn.Namespace, rel.Conn.Name, rel.ID)
 }
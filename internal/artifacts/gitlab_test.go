@@ -2,8 +2,17 @@ package artifacts
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
 	"github.com/xanzy/go-gitlab"
 )
 
@@ -37,3 +46,61 @@ func TestGitLabClient_ProjectID(t *testing.T) {
 		})
 	}
 }
+
+func TestGitLabRelease_Upload(t *testing.T) {
+	var (
+		calls []string
+		lock  sync.Mutex
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/v4/projects/") {
+			return
+		}
+
+		lock.Lock()
+		calls = append(calls, fmt.Sprintf("%s %s", r.Method, r.URL.EscapedPath()))
+		lock.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/uploads"):
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"url": "/uploads/abc/dist.tar.gz"}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `[{"id": 5, "name": "dist.tar.gz"}, {"id": 6, "name": "other.tar.gz"}]`)
+		case r.Method == http.MethodDelete:
+			fmt.Fprint(w, `{"id": 5, "name": "dist.tar.gz"}`)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": 7, "name": "dist.tar.gz"}`)
+		}
+	}))
+	defer server.Close()
+
+	location := path.Join(t.TempDir(), "dist.tar.gz")
+	if err := os.WriteFile(location, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := (&GitLabService{}).New(context.Background(), server.URL, "token", "owner", "name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel := &GitLabRelease{Conn: conn.(*GitLabClient), ID: "Release 1.0.0", Tag: "v1.0.0", Ver: "1.0.0"}
+
+	if err := rel.Upload(&ctx.Artifact{Filename: "dist.tar.gz", Location: location}); err != nil {
+		t.Fatalf("GitLabRelease.Upload() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"POST /api/v4/projects/owner%2Fname/uploads",
+		"GET /api/v4/projects/owner%2Fname/releases/v1%2E0%2E0/assets/links",
+		"DELETE /api/v4/projects/owner%2Fname/releases/v1%2E0%2E0/assets/links/5",
+		"POST /api/v4/projects/owner%2Fname/releases/v1%2E0%2E0/assets/links",
+	}
+
+	if diff := deep.Equal(calls, want); diff != nil {
+		t.Errorf("GitLabRelease.Upload() calls %v", diff)
+	}
+}
@@ -0,0 +1,90 @@
+package artifacts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/xanzy/go-gitlab"
+)
+
+// retryable marks errors of remote calls as modules.RetryableError, by
+// the HTTP response received: rate limits, and server errors can be
+// retried. Other errors are returned as is.
+func retryable(err error) error {
+	var (
+		rateErr   *github.RateLimitError
+		abuseErr  *github.AbuseRateLimitError
+		githubErr *github.ErrorResponse
+		gitlabErr *gitlab.ErrorResponse
+	)
+
+	switch {
+	case errors.As(err, &rateErr):
+		return &modules.RetryableError{
+			Class: modules.RetryRateLimit,
+			After: time.Until(rateErr.Rate.Reset.Time),
+			Err:   err,
+		}
+	case errors.As(err, &abuseErr):
+		after := rateLimitDelay(abuseErr.Response)
+		if abuseErr.RetryAfter != nil {
+			after = *abuseErr.RetryAfter
+		}
+
+		return &modules.RetryableError{Class: modules.RetryRateLimit, After: after, Err: err}
+	case errors.As(err, &githubErr):
+		return responseError(err, githubErr.Response)
+	case errors.As(err, &gitlabErr):
+		return responseError(err, gitlabErr.Response)
+	}
+
+	return err
+}
+
+// responseError returns err as retryable, if the response is a rate
+// limit, or a server error
+func responseError(err error, resp *http.Response) error {
+	if resp == nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return &modules.RetryableError{Class: modules.RetryRateLimit, After: rateLimitDelay(resp), Err: err}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return &modules.RetryableError{Class: modules.RetryServer, Err: err}
+	}
+
+	return err
+}
+
+// rateLimitDelay returns the delay a rate limit response asks for, by its
+// Retry-After, or rate limit reset headers
+func rateLimitDelay(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+
+		if date, err := http.ParseTime(value); err == nil {
+			return time.Until(date)
+		}
+	}
+
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if reset, err := strconv.ParseInt(resp.Header.Get(header), 10, 64); err == nil {
+			return time.Until(time.Unix(reset, 0))
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/xanzy/go-gitlab"
+)
+
+func Test_retryable(t *testing.T) {
+	response := func(status int, headers ...string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+
+		for idx := 0; idx+1 < len(headers); idx += 2 {
+			resp.Header.Set(headers[idx], headers[idx+1])
+		}
+
+		return resp
+	}
+	retryAfter := 30 * time.Second
+
+	tests := []struct {
+		name  string
+		err   error
+		class string
+		after time.Duration
+	}{
+		{
+			name: "not a response error",
+			err:  errors.New("failure"),
+		},
+		{
+			name: "client error",
+			err:  &github.ErrorResponse{Response: response(http.StatusUnprocessableEntity)},
+		},
+		{
+			name:  "server error",
+			err:   &github.ErrorResponse{Response: response(http.StatusBadGateway)},
+			class: modules.RetryServer,
+		},
+		{
+			name:  "too many requests",
+			err:   &gitlab.ErrorResponse{Response: response(http.StatusTooManyRequests, "Retry-After", "20")},
+			class: modules.RetryRateLimit,
+			after: 20 * time.Second,
+		},
+		{
+			name:  "abuse rate limit",
+			err:   &github.AbuseRateLimitError{Response: response(http.StatusForbidden), RetryAfter: &retryAfter},
+			class: modules.RetryRateLimit,
+			after: retryAfter,
+		},
+		{
+			name: "forbidden",
+			err:  &github.ErrorResponse{Response: response(http.StatusForbidden, "X-RateLimit-Remaining", "10")},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := retryable(tt.err)
+
+			var retryableErr *modules.RetryableError
+			if !errors.As(err, &retryableErr) {
+				if tt.class != "" {
+					t.Errorf("retryable() = %v, want class %s", err, tt.class)
+				}
+
+				return
+			}
+
+			if retryableErr.Class != tt.class || retryableErr.After != tt.after {
+				t.Errorf(
+					"retryable() class %q after %s, want %q after %s",
+					retryableErr.Class,
+					retryableErr.After,
+					tt.class,
+					tt.after,
+				)
+			}
+		})
+	}
+}
@@ -1,38 +1,39 @@
 package modules
 
 import (
+	"crypto"
 	//nolint: gosec
-	"crypto/md5"
+	_ "crypto/md5"
 	//nolint: gosec
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"fmt"
-	"hash"
 
+	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
 type (
 	HashAlgorithm struct {
-		Algo    string
-		Factory func() hash.Hash
+		Algo string
+		Hash crypto.Hash
 	}
 )
 
 func (algo *HashAlgorithm) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.ScalarNode {
-		return fmt.Errorf("algorithm is `%v`, not a scalar", node.Kind)
+		return modules.NewConfigError(node, fmt.Errorf("algorithm is `%v`, not a scalar", node.Kind))
 	}
 
 	var hasherString string
 	if err := node.Decode(&hasherString); err != nil {
-		return fmt.Errorf("algorithm cannot be decoded: %w", err)
+		return modules.NewConfigError(node, fmt.Errorf("algorithm cannot be decoded: %w", err))
 	}
 
 	newAlgo, err := NewHashAlgorithm(hasherString)
 	if err != nil {
-		return err
+		return modules.NewConfigError(node, err)
 	}
 
 	*algo = *newAlgo
@@ -41,19 +42,29 @@ func (algo *HashAlgorithm) UnmarshalYAML(node *yaml.Node) error {
 }
 
 func NewHashAlgorithm(hasher string) (*HashAlgorithm, error) {
-	factoryMap := map[string]func() hash.Hash{
-		"md5":    md5.New,
-		"sha1":   sha1.New,
-		"sha256": sha256.New,
-		"sha512": sha512.New,
+	hashMap := map[string]crypto.Hash{
+		"md5":    crypto.MD5,
+		"sha1":   crypto.SHA1,
+		"sha256": crypto.SHA256,
+		"sha512": crypto.SHA512,
 	}
 
-	factory, ok := factoryMap[hasher]
+	hash, ok := hashMap[hasher]
 	if !ok {
 		return nil, fmt.Errorf("algorithm `%s` not registered", hasher)
 	}
 
-	return &HashAlgorithm{Algo: hasher, Factory: factory}, nil
+	return &HashAlgorithm{Algo: hasher, Hash: hash}, nil
+}
+
+// MarshalYAML returns the algorithm's name
+func (algo HashAlgorithm) MarshalYAML() (interface{}, error) {
+	return algo.Algo, nil
+}
+
+// Enum returns valid algorithm names
+func (HashAlgorithm) Enum() []string {
+	return []string{"md5", "sha1", "sha256", "sha512"}
 }
 
 func (algo *HashAlgorithm) String() string {
@@ -0,0 +1,158 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+// ArchiveFile is a static file entry of archives. In YAML, it is either a
+// glob, or a mapping of its fields.
+type ArchiveFile struct {
+	// Src is a glob of files, directories, and symlinks to be added.
+	// Directories are added recursively, symlinks are kept as symlinks.
+	Src string
+	// Dst is a `modules.TemplateData` template of the destination inside
+	// the common directory. If Src is a single file, or directory, and
+	// Dst doesn't end with `/`, Dst is its new name. Otherwise, Dst is a
+	// directory matches are put into, with their paths. Default: the path
+	// of matches.
+	Dst string
+	// Mode is the mode of file entries, like 0644. Default: the archive's
+	// file mode.
+	Mode FileMode
+	// StripParent puts matches into Dst without their parent directories.
+	StripParent bool `yaml:"strip_parent"`
+	// Skip specifies OS-arch combinations, or artifact filters of
+	// archives the entry is not added to.
+	Skip modules.Filters
+}
+
+// nolint: gochecknoglobals
+var archiveFileKeys = map[string]bool{
+	"src":          true,
+	"dst":          true,
+	"mode":         true,
+	"strip_parent": true,
+	"skip":         true,
+}
+
+// UnmarshalYAML decodes a glob, or a mapping of file entry fields
+func (file *ArchiveFile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*file = ArchiveFile{Src: node.Value}
+
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return modules.NewConfigError(node, fmt.Errorf("file is `%v`, not a glob, or a mapping", node.Tag))
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		if !archiveFileKeys[key.Value] {
+			return modules.NewConfigError(key, fmt.Errorf("unknown file field %q", key.Value))
+		}
+	}
+
+	type plain ArchiveFile
+
+	return node.Decode((*plain)(file))
+}
+
+// MarshalYAML returns the glob of entries having no other fields set
+func (file ArchiveFile) MarshalYAML() (interface{}, error) {
+	if file.Dst == "" && file.Mode == 0 && !file.StripParent && len(file.Skip) == 0 {
+		return file.Src, nil
+	}
+
+	type plain ArchiveFile
+
+	return plain(file), nil
+}
+
+// validateFiles checks file entries of archives
+func validateFiles(files []*ArchiveFile) error {
+	var errs modules.Errors
+
+	for idx, file := range files {
+		prefix := fmt.Sprintf("files[%d].", idx)
+
+		errs.Add(modules.RequireField(prefix+"src", file.Src))
+
+		if _, err := filepath.Match(file.Src, ""); err != nil {
+			errs.Add(modules.NewFieldError(prefix+"src", "invalid pattern %q", file.Src))
+		}
+
+		errs.Extend(file.Skip.Validate(prefix + "skip"))
+	}
+
+	return errs.Err()
+}
+
+// entries returns archive entries of the file, under commonDir
+func (file *ArchiveFile) entries(commonDir string) ([]*archiveEntry, error) {
+	matches, err := filepath.Glob(file.Src)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := file.Dst != "" && !strings.HasSuffix(file.Dst, "/") && !isPattern(file.Src)
+	entries := []*archiveEntry{}
+
+	for _, match := range matches {
+		root := filepath.ToSlash(match)
+
+		switch {
+		case renamed:
+			root = file.Dst
+		case file.Dst != "" && file.StripParent:
+			root = path.Join(file.Dst, path.Base(root))
+		case file.Dst != "":
+			root = path.Join(file.Dst, root)
+		case file.StripParent:
+			root = path.Base(root)
+		}
+
+		root = path.Clean(root)
+		if root == ".." || strings.HasPrefix(root, "../") || path.IsAbs(root) {
+			return nil, fmt.Errorf("destination %q of %s is outside of the archive", root, match)
+		}
+
+		err := filepath.Walk(match, func(source string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(match, source)
+			if err != nil {
+				return err
+			}
+
+			name := path.Join(commonDir, root, filepath.ToSlash(rel))
+			if name == "." {
+				return nil
+			}
+
+			entries = append(entries, &archiveEntry{
+				name:   name,
+				source: source,
+				info:   info,
+				mode:   os.FileMode(file.Mode).Perm(),
+			})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
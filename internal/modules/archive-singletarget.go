@@ -0,0 +1,404 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// archiveSingleTarget writes an archive of artifacts of a single OS-arch
+// combination, in tar, or zip format
+type archiveSingleTarget struct {
+	CommonDir   string
+	Compression Compression
+	DirsWritten map[string]bool
+	Files       []*ArchiveFile
+	// Format is the archive format: tar, or zip. Default: tar.
+	Format string
+	ID     string
+	// ModTime is the modification time of directory entries, of zip
+	// entries, and of normalized entries. It is set from
+	// SOURCE_DATE_EPOCH, or from the commit time for zip archives, and
+	// normalized archives.
+	ModTime  time.Time
+	osarch   *ctx.OsArch
+	Output   string
+	Settings ArchiveSettings
+	Targets  *ctx.Artifacts
+}
+
+// archiveEntry is a file, directory, or symlink to be written into an
+// archive
+type archiveEntry struct {
+	name   string
+	source string
+	// info is the file info of the source, if known. Sources without file
+	// info are followed, if they are symlinks.
+	info os.FileInfo
+	// mode overrides the file mode of archive settings, if set
+	mode os.FileMode
+}
+
+// newArchiveTarget renders CommonDir, and Output of a target template for
+// a set of artifacts of the same OS-arch. ext is the `{{.Ext}}` value of
+// templates.
+func newArchiveTarget(
+	cx context.Context,
+	tmpl *archiveSingleTarget,
+	ext string,
+	artifacts *ctx.Artifacts,
+) (*archiveSingleTarget, error) {
+	art := (*artifacts)[0]
+	ret := &archiveSingleTarget{
+		Compression: tmpl.Compression,
+		DirsWritten: map[string]bool{},
+		Format:      tmpl.Format,
+		ID:          tmpl.ID,
+		osarch:      art.OsArch,
+		Settings:    tmpl.Settings,
+		Targets:     artifacts,
+	}
+
+	if err := ret.setModTime(cx); err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	td.OSArch = ret.osarch
+	td.Ext = ext
+
+	for _, task := range []struct {
+		name   string
+		source string
+		target *string
+	}{
+		{"commondir", tmpl.CommonDir, &ret.CommonDir},
+		{"output", tmpl.Output, &ret.Output},
+	} {
+		var err error
+
+		*task.target, err = td.Parse("archive:"+ret.format(), task.source)
+		if err != nil {
+			return nil, fmt.Errorf("rendering %q: %w", task.source, err)
+		}
+	}
+
+	ret.Output = path.Clean(ret.Output)
+	archive := ret.artifact("")
+
+	for _, file := range tmpl.Files {
+		if file.Skip.Match(archive) {
+			continue
+		}
+
+		rendered := *file
+
+		rendered.Dst, err = td.Parse("archive:"+ret.format(), file.Dst)
+		if err != nil {
+			return nil, fmt.Errorf("rendering %q: %w", file.Dst, err)
+		}
+
+		ret.Files = append(ret.Files, &rendered)
+	}
+
+	return ret, nil
+}
+
+// setModTime sets the modification time of entries from
+// SOURCE_DATE_EPOCH, or from the commit time for zip archives, and for
+// normalized archives. Other archives use the current time for directory
+// entries.
+func (target *archiveSingleTarget) setModTime(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if target.format() != formatZip && !target.Settings.Normalize {
+		target.ModTime = time.Now()
+
+		return nil
+	}
+
+	modTime, err := context.SourceDate()
+	if err != nil {
+		return err
+	}
+
+	if modTime.IsZero() && target.Settings.Normalize {
+		return fmt.Errorf(
+			"cannot normalize archive: modification time is unknown, set %s, or use setup:git",
+			ctx.SourceDateEpochVar,
+		)
+	}
+
+	target.ModTime = modTime
+
+	return nil
+}
+
+// format returns the archive format of the target
+func (target *archiveSingleTarget) format() string {
+	if target.Format == "" {
+		return formatTar
+	}
+
+	return target.Format
+}
+
+func (target *archiveSingleTarget) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	artifact := target.artifact(context.TargetDir)
+	archiveFile := artifact.Location
+
+	archive, err := os.Create(archiveFile)
+	if err != nil {
+		return fmt.Errorf("cannot create archive file %s: %w", archiveFile, err)
+	}
+
+	err = target.write(cx, archive)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(archiveFile)
+		return fmt.Errorf("writing %s: %w", archiveFile, err)
+	}
+
+	if err := artifact.Stat(); err != nil {
+		return err
+	}
+
+	context.AddArtifact(artifact)
+
+	return nil
+}
+
+// write writes the archive into w. It stops when cx is done.
+func (target *archiveSingleTarget) write(cx context.Context, w io.Writer) error {
+	var archive archiveWriter
+
+	if target.format() == formatZip {
+		archive = newZipArchive(w, target.ModTime)
+	} else {
+		tarArchive, err := newTarArchive(w, target.Compression, &target.Settings)
+		if err != nil {
+			return err
+		}
+
+		archive = tarArchive
+	}
+
+	err := target.writeContents(cx, archive)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (target *archiveSingleTarget) writeContents(cx context.Context, archive archiveWriter) error {
+	entries, err := target.entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
+		if err := target.writeDirs(archive, path.Dir(entry.name)); err != nil {
+			return err
+		}
+
+		if err := target.writeEntry(archive, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entries returns files to be written into the archive: artifacts first,
+// then static files, or all of them sorted by name in normalized archives
+func (target *archiveSingleTarget) entries() ([]*archiveEntry, error) {
+	entries := []*archiveEntry{}
+
+	for _, artifact := range *target.Targets {
+		entries = append(entries, &archiveEntry{
+			name:   path.Join(target.CommonDir, artifact.Filename),
+			source: artifact.Location,
+		})
+	}
+
+	for _, file := range target.Files {
+		fileEntries, err := file.entries(target.CommonDir)
+		if err != nil {
+			return nil, fmt.Errorf("adding %s: %w", file.Src, err)
+		}
+
+		entries = append(entries, fileEntries...)
+	}
+
+	if target.Settings.Normalize {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].name < entries[j].name
+		})
+	}
+
+	return entries, nil
+}
+
+// artifact returns the archive artifact the target creates
+func (target *archiveSingleTarget) artifact(targetDir string) *ctx.Artifact {
+	return &ctx.Artifact{
+		Filename: target.Output,
+		Location: path.Join(targetDir, target.Output),
+		ID:       target.ID,
+		Kind:     ctx.KindArchive,
+		OsArch:   target.osarch,
+		Extra:    map[string]interface{}{"format": target.format()},
+	}
+}
+
+// writeEntry writes an entry as a directory, a symlink, or a file
+func (target *archiveSingleTarget) writeEntry(archive archiveWriter, entry *archiveEntry) error {
+	fi := entry.info
+
+	if fi == nil {
+		var err error
+
+		fi, err = os.Stat(entry.source)
+		if err != nil {
+			return fmt.Errorf("can't stat file %s: %w", entry.source, err)
+		}
+	}
+
+	switch {
+	case fi.IsDir():
+		return target.writeDir(archive, entry.name)
+	case fi.Mode()&os.ModeSymlink != 0:
+		return target.writeSymlink(archive, entry.name, entry.source, fi)
+	}
+
+	return target.writeFile(archive, entry, fi)
+}
+
+func (target *archiveSingleTarget) writeSymlink(archive archiveWriter, destpath, source string, fi os.FileInfo) error {
+	link, err := os.Readlink(source)
+	if err != nil {
+		return err
+	}
+
+	if target.Settings.Normalize {
+		fi = &entryInfo{name: fi.Name(), mode: os.ModeSymlink | 0o777, modTime: target.ModTime}
+	}
+
+	return archive.Symlink(destpath, fi, filepath.ToSlash(link))
+}
+
+func (target *archiveSingleTarget) writeFile(archive archiveWriter, entry *archiveEntry, fi os.FileInfo) error {
+	destpath, source := entry.name, entry.source
+
+	mode, ok := target.Settings.fileMode(fi.Mode())
+	if entry.mode != 0 {
+		mode, ok = entry.mode, true
+	}
+
+	if ok {
+		modTime := fi.ModTime()
+		if target.Settings.Normalize {
+			modTime = target.ModTime
+		}
+
+		fi = &entryInfo{name: fi.Name(), size: fi.Size(), mode: mode, modTime: modTime}
+	}
+
+	writer, err := archive.Create(destpath, fi)
+	if err != nil {
+		return err
+	}
+
+	sourceReader, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+
+	defer sourceReader.Close()
+
+	buf := make([]byte, 4096)
+
+	if n, err := io.CopyBuffer(writer, sourceReader, buf); err != nil {
+		return fmt.Errorf(
+			"copying %s to archive %s (%d bytes written, %d bytes reported): %w",
+			source,
+			destpath,
+			n,
+			fi.Size(),
+			err,
+		)
+	}
+
+	return nil
+}
+
+func (target *archiveSingleTarget) writeDirs(archive archiveWriter, fullpath string) error {
+	if fullpath == "." {
+		return nil
+	}
+
+	dirs := []string{fullpath}
+
+	for {
+		fullpath = path.Dir(fullpath)
+		if fullpath == "." {
+			break
+		}
+
+		dirs = append(dirs, fullpath)
+	}
+
+	for i := range dirs {
+		dirname := dirs[len(dirs)-i-1]
+
+		if err := target.writeDir(archive, dirname); err != nil {
+			return fmt.Errorf("cannot create directory %s: %w", dirname, err)
+		}
+	}
+
+	return nil
+}
+
+func (target *archiveSingleTarget) writeDir(archive archiveWriter, dirname string) error {
+	if _, ok := target.DirsWritten[dirname]; ok {
+		return nil
+	}
+
+	info := newDirInfo(dirname, target.Settings.dirMode(), target.ModTime)
+
+	if err := archive.Mkdir(dirname, info); err != nil {
+		return err
+	}
+
+	target.DirsWritten[dirname] = true
+
+	return nil
+}
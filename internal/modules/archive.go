@@ -0,0 +1,311 @@
+package modules
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatTar = "tar"
+	formatZip = "zip"
+)
+
+type (
+	// ArchiveSettings are settings of archive entries, shared by archive
+	// modules
+	ArchiveSettings struct {
+		// DirMode is the mode of directory entries. Default: 0755.
+		DirMode FileMode `yaml:"dir_mode"`
+		// FileMode is the mode of file entries, like 0644. Executable
+		// files get execute permissions wherever FileMode has read
+		// permissions. Default: modes of source files, or 0644 if
+		// Normalize is set.
+		FileMode FileMode `yaml:"file_mode"`
+		// Normalize makes archives reproducible: entries are sorted by
+		// name, owned by root (0/0), and their modification time is
+		// SOURCE_DATE_EPOCH, or the commit time of the current commit.
+		Normalize bool
+		// TarFormat is the format of tar archives: pax, gnu, or ustar.
+		// Default: the simplest format fitting the entries.
+		TarFormat string `yaml:"tar_format"`
+	}
+
+	// FileMode is a YAML representation of permission bits, in octal
+	// format, like 0755
+	FileMode os.FileMode
+
+	// archiveWriter adds entries to an archive
+	archiveWriter interface {
+		// Create adds a file entry, and returns the writer of its contents
+		Create(name string, info os.FileInfo) (io.Writer, error)
+		// Mkdir adds a directory entry
+		Mkdir(name string, info os.FileInfo) error
+		// Symlink adds a symbolic link entry pointing to link
+		Symlink(name string, info os.FileInfo, link string) error
+		// Close finishes the archive, without closing the underlying
+		// writer
+		Close() error
+	}
+
+	// entryInfo is the file info of archive entries, which are
+	// normalized, or don't exist in the file system
+	entryInfo struct {
+		name    string
+		size    int64
+		mode    os.FileMode
+		modTime time.Time
+	}
+
+	// tarArchive writes compressed tar archives
+	tarArchive struct {
+		compressor io.WriteCloser
+		format     tar.Format
+		normalize  bool
+		tw         *tar.Writer
+	}
+
+	// zipArchive writes zip archives, with deflated entries of the same
+	// modification time
+	zipArchive struct {
+		modTime time.Time
+		zw      *zip.Writer
+	}
+)
+
+// nolint: gochecknoglobals
+var (
+	zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tarFormats = map[string]tar.Format{
+		"":      tar.FormatUnknown,
+		"gnu":   tar.FormatGNU,
+		"pax":   tar.FormatPAX,
+		"ustar": tar.FormatUSTAR,
+	}
+)
+
+// validate checks archive settings
+func (settings *ArchiveSettings) validate() error {
+	if _, ok := tarFormats[settings.TarFormat]; !ok {
+		return modules.NewFieldError("tar_format", "invalid value %q, valid values are pax, gnu, and ustar", settings.TarFormat)
+	}
+
+	return nil
+}
+
+// dirMode returns the mode of directory entries
+func (settings *ArchiveSettings) dirMode() os.FileMode {
+	if settings.DirMode == 0 {
+		return os.ModeDir | 0o755
+	}
+
+	return os.ModeDir | os.FileMode(settings.DirMode).Perm()
+}
+
+// fileMode returns the mode of a file entry from the mode of its source.
+// It returns false, if the mode of the source is kept.
+func (settings *ArchiveSettings) fileMode(mode os.FileMode) (os.FileMode, bool) {
+	perm := os.FileMode(settings.FileMode).Perm()
+
+	if perm == 0 {
+		if !settings.Normalize {
+			return mode, false
+		}
+
+		perm = 0o644
+	}
+
+	if mode&0o111 != 0 {
+		perm |= (perm & 0o444) >> 2
+	}
+
+	return perm, true
+}
+
+// UnmarshalYAML decodes permission bits in octal format
+func (mode *FileMode) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return modules.NewConfigError(node, fmt.Errorf("file mode is `%v`, not scalar", node.Kind))
+	}
+
+	value, err := strconv.ParseUint(strings.TrimPrefix(node.Value, "0o"), 8, 32)
+	if err != nil || value > 0o777 {
+		return modules.NewConfigError(node, fmt.Errorf("invalid file mode %q, expecting octal permissions, like 0644", node.Value))
+	}
+
+	*mode = FileMode(value)
+
+	return nil
+}
+
+// MarshalYAML returns permission bits in octal format, or nil, if they
+// are not set
+func (mode FileMode) MarshalYAML() (interface{}, error) {
+	if mode == 0 {
+		return nil, nil
+	}
+
+	return fmt.Sprintf("%04o", uint32(mode)), nil
+}
+
+func (info *entryInfo) Name() string       { return info.name }
+func (info *entryInfo) Size() int64        { return info.size }
+func (info *entryInfo) Mode() os.FileMode  { return info.mode }
+func (info *entryInfo) ModTime() time.Time { return info.modTime }
+func (info *entryInfo) IsDir() bool        { return info.mode.IsDir() }
+func (info *entryInfo) Sys() interface{}   { return nil }
+
+// newDirInfo returns the file info of a directory entry
+func newDirInfo(name string, mode os.FileMode, modTime time.Time) *entryInfo {
+	return &entryInfo{name: path.Base(name), mode: mode, modTime: modTime}
+}
+
+func newTarArchive(w io.Writer, compression Compression, settings *ArchiveSettings) (*tarArchive, error) {
+	compressor, err := compression.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tarArchive{
+		compressor: compressor,
+		format:     tarFormats[settings.TarFormat],
+		normalize:  settings.Normalize,
+		tw:         tar.NewWriter(compressor),
+	}, nil
+}
+
+// header returns a tar header of an entry. Normalized entries are owned
+// by root.
+func (archive *tarArchive) header(name string, info os.FileInfo, link string) (*tar.Header, error) {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr.Name = name
+	hdr.Format = archive.format
+
+	if archive.normalize {
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "root", "root"
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+	}
+
+	return hdr, nil
+}
+
+func (archive *tarArchive) Create(name string, info os.FileInfo) (io.Writer, error) {
+	hdr, err := archive.header(name, info, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := archive.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+
+	return archive.tw, nil
+}
+
+func (archive *tarArchive) Mkdir(name string, info os.FileInfo) error {
+	hdr, err := archive.header(name+"/", info, "")
+	if err != nil {
+		return err
+	}
+
+	return archive.tw.WriteHeader(hdr)
+}
+
+func (archive *tarArchive) Symlink(name string, info os.FileInfo, link string) error {
+	hdr, err := archive.header(name, info, link)
+	if err != nil {
+		return err
+	}
+
+	return archive.tw.WriteHeader(hdr)
+}
+
+func (archive *tarArchive) Close() error {
+	err := archive.tw.Close()
+	if closeErr := archive.compressor.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// newZipArchive returns a zip archive writer. Entries are set to modTime,
+// or to 1980-01-01, if modTime is earlier.
+func newZipArchive(w io.Writer, modTime time.Time) *zipArchive {
+	if modTime.Before(zipEpoch) {
+		modTime = zipEpoch
+	}
+
+	return &zipArchive{modTime: modTime, zw: zip.NewWriter(w)}
+}
+
+// header returns a zip header of an entry, keeping its Unix mode
+func (archive *zipArchive) header(name string, info os.FileInfo) (*zip.FileHeader, error) {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr.Name = name
+	hdr.Modified = archive.modTime
+
+	return hdr, nil
+}
+
+func (archive *zipArchive) Create(name string, info os.FileInfo) (io.Writer, error) {
+	hdr, err := archive.header(name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr.Method = zip.Deflate
+
+	return archive.zw.CreateHeader(hdr)
+}
+
+func (archive *zipArchive) Mkdir(name string, info os.FileInfo) error {
+	hdr, err := archive.header(name+"/", info)
+	if err != nil {
+		return err
+	}
+
+	_, err = archive.zw.CreateHeader(hdr)
+
+	return err
+}
+
+// Symlink adds a symbolic link entry, storing link as its contents
+func (archive *zipArchive) Symlink(name string, info os.FileInfo, link string) error {
+	hdr, err := archive.header(name, info)
+	if err != nil {
+		return err
+	}
+
+	entry, err := archive.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(entry, link)
+
+	return err
+}
+
+func (archive *zipArchive) Close() error {
+	return archive.zw.Close()
+}
@@ -14,9 +14,9 @@ import (
 
 // Artifact is a publish module for artifact storage servers like GitHub, or GitLab.
 type Artifact struct {
-	// Builds specifies which build names should be uploaded to the
-	// github release.
-	Builds []string
+	// Builds selects artifacts to be uploaded to the release, by their
+	// IDs, or by artifact filters.
+	Builds modules.Selector
 	// Name specifies the repository's name. No default, no detection (yet).
 	// Required.
 	Name string
@@ -26,6 +26,10 @@ type Artifact struct {
 	// ReleaseName specifies the release's name, using modules.TemplateData.
 	// Default: "{{.Version}}"
 	ReleaseName string `yaml:"release_name,omitempty"`
+	// Retry specifies the retry policy of remote calls. Default: 3
+	// attempts, starting with 2s backoff, retrying network, server, and
+	// rate limit errors.
+	Retry *modules.Retry
 	// ReleaseNotes selects the artifact to be used for release notes.
 	// It must select a single artifact.
 	ReleaseNotes string `yaml:"release_notes"`
@@ -53,11 +57,56 @@ func NewArtifact() modules.Pluggable {
 
 	return &Artifact{
 		ReleaseName:   "{{.Version}}",
+		Retry:         modules.DefaultRetry(),
 		SkipTLSVerify: false,
 		Storage:       storage,
 	}
 }
 
+// Describe documents Artifact module
+func (*Artifact) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Creates or updates a release on an artifact storage, and uploads artifacts",
+		Fields: map[string]string{
+			"builds":          "Artifact IDs, or artifact filters to be uploaded",
+			"name":            "Repository name",
+			"owner":           "Repository owner organization",
+			"release_name":    "Release name template",
+			"release_notes":   "Artifact ID of release notes",
+			"retry":           "Retry policy of remote calls: attempts, backoff, max_backoff, and error classes (on)",
+			"skip_tls_verify": "Disables TLS server certificate verification",
+			"storage":         "Artifact storage service",
+			"token_env":       "Environment variable containing auth token",
+			"token_file":      "File containing auth token",
+			"url":             "Artifact storage base URL, for on-premises services only",
+		},
+	}
+}
+
+// Validate checks release settings
+func (mod *Artifact) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireField("name", mod.Name))
+	errs.Add(modules.RequireField("owner", mod.Owner))
+	errs.Add(modules.RequireField("release_notes", mod.ReleaseNotes))
+	errs.Extend(mod.Builds.Validate("builds"))
+	errs.Extend(mod.Retry.Validate("retry"))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs to be uploaded, including release notes
+func (mod *Artifact) Consumes() []string {
+	ids := mod.Builds.IDs()
+
+	if mod.ReleaseNotes != "" {
+		ids = append(ids, mod.ReleaseNotes)
+	}
+
+	return ids
+}
+
 // Run uploads previously created artifact into artifact storage provided
 // by artifacts.Storage.
 func (mod *Artifact) Run(cx context.Context) error {
@@ -68,7 +117,7 @@ func (mod *Artifact) Run(cx context.Context) error {
 
 	var notes string
 
-	relNotes := []*ctx.Artifact(*context.Artifacts.ByID(mod.ReleaseNotes))
+	relNotes := []*ctx.Artifact(*context.ListArtifacts().ByID(mod.ReleaseNotes))
 
 	switch len(relNotes) {
 	case 0:
@@ -99,18 +148,29 @@ func (mod *Artifact) Run(cx context.Context) error {
 		return fmt.Errorf("parsing release name: %w", err)
 	}
 
-	releaser, err := client.NewReleaser(context.Git.Tag, context.Git.Ref, context.Version)
-	if err != nil {
+	var releaser artifacts.Releaser
+
+	if err := mod.Retry.Do(cx, "setting up releaser", func() error {
+		releaser, err = client.NewReleaser(context.Git.Tag, context.Git.Ref, context.Version)
+
+		return err
+	}); err != nil {
 		return fmt.Errorf("setting up releaser: %w", err)
 	}
 
-	if err := releaser.Release(name, notes); err != nil {
+	if err := mod.Retry.Do(cx, "releasing", func() error {
+		return releaser.Release(name, notes)
+	}); err != nil {
 		return fmt.Errorf("releasing: %w", err)
 	}
 
-	for _, build := range context.Artifacts.OsArchByIDs(mod.Builds, nil) {
+	for _, build := range context.ListArtifacts().OsArchBySelector(&mod.Builds.Selector) {
 		for _, item := range *build {
-			if err := releaser.Upload(item); err != nil {
+			item := item
+
+			if err := mod.Retry.Do(cx, "uploading "+item.Filename, func() error {
+				return releaser.Upload(item)
+			}); err != nil {
 				return fmt.Errorf("uploading file %s to release %v: %w", item.Location, releaser, err)
 			}
 		}
@@ -119,6 +179,41 @@ func (mod *Artifact) Run(cx context.Context) error {
 	return nil
 }
 
+// Plan describes the release to be created, and files to be uploaded
+func (mod *Artifact) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	name, err := td.Parse("release-name", mod.ReleaseName)
+	if err != nil {
+		return fmt.Errorf("parsing release name: %w", err)
+	}
+
+	tag := context.Git.Tag
+	if tag == "" {
+		tag = context.Version
+	}
+
+	plan.AddRemoteCall("create or update release %q (tag %s) at %s/%s", name, tag, mod.Owner, mod.Name)
+
+	builds := context.ListArtifacts().OsArchBySelector(&mod.Builds.Selector)
+
+	for _, osarch := range sortedOsArchs(builds) {
+		for _, item := range *builds[osarch] {
+			plan.AddRemoteCall("upload %s to release %q", item.Location, name)
+		}
+	}
+
+	return nil
+}
+
 // NewClient returns a new Storage connection
 func (mod *Artifact) NewClient(cx context.Context) (artifacts.Connection, error) {
 	return mod.Storage.New(
@@ -2,9 +2,8 @@ package modules
 
 import (
 	"context"
+	"crypto"
 	"fmt"
-	"hash"
-	"io"
 	"log"
 	"os"
 	"path"
@@ -17,17 +16,18 @@ import (
 type Checksum struct {
 	// Algorithm specifies checksum algorithm
 	Algorithm HashAlgorithm
-	// Builds specifies a build names to find related artifacts to
-	// calculate checksums of.
-	Builds []string
+	// Builds selects artifacts to calculate checksums of, by their IDs,
+	// or by artifact filters.
+	Builds modules.Selector
 	// ID specifies the checksum's name, as it stores in artifacts.
 	// Default: "checksum"
 	ID string
 	// Output is where the checksum file is going to be created
 	// Default: "{{.ProjectName}}-{{.Version}}-checksums.txt"
 	Output string
-	// Skip specifies which os-arch items should be skipped
-	Skip []string
+	// Skip specifies which os-arch items, or artifact filters should be
+	// skipped
+	Skip modules.Skip
 }
 
 func NewChecksum() modules.Pluggable {
@@ -35,12 +35,49 @@ func NewChecksum() modules.Pluggable {
 
 	return &Checksum{
 		Algorithm: *algo,
-		Builds:    []string{"artifact"},
+		Builds:    modules.SelectIDs("artifact"),
 		ID:        "checksum",
 		Output:    "{{.ProjectName}}-{{.Version}}-checksums.txt",
 	}
 }
 
+// Describe documents Checksum module
+func (*Checksum) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Writes a checksum file of artifacts",
+		Fields: map[string]string{
+			"algorithm": "Checksum algorithm",
+			"builds":    "Artifact IDs, or artifact filters to calculate checksums of",
+			"id":        "Resulting artifact ID",
+			"output":    "Checksum file name template",
+			"skip":      "OS-arch combinations, or artifact filters to be skipped",
+		},
+	}
+}
+
+// Validate checks checksum settings
+func (checksum *Checksum) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", checksum.Builds))
+	errs.Extend(checksum.Builds.Validate("builds"))
+	errs.Extend(checksum.Skip.Validate("skip"))
+	errs.Add(modules.RequireField("id", checksum.ID))
+	errs.Add(modules.RequireField("output", checksum.Output))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs to be checksummed
+func (checksum *Checksum) Consumes() []string {
+	return checksum.Builds.IDs()
+}
+
+// Produces returns the artifact ID of the checksum file
+func (checksum *Checksum) Produces() []string {
+	return []string{checksum.ID}
+}
+
 func (checksum *Checksum) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
@@ -52,20 +89,23 @@ func (checksum *Checksum) Run(cx context.Context) error {
 		return fmt.Errorf("generating checksum filename: %w", err)
 	}
 
-	checksumFilename := path.Join(context.TargetDir, output)
-
-	artifactMap := context.Artifacts.OsArchByIDs(checksum.Builds, checksum.Skip)
+	artifactMap := context.ListArtifacts().OsArchBySelector(checksum.Builds.Skipping(checksum.Skip))
 	if len(artifactMap) == 0 {
 		return nil
 	}
 
-	checksums := []string{}
+	artifact := checksum.artifact(context.TargetDir, output)
+	checksumFilename := artifact.Location
 
-	hasher := checksum.Algorithm.Factory()
+	checksums := []string{}
 
 	for osarch := range artifactMap {
 		for _, artifact := range *artifactMap[osarch] {
-			sum, err := checksumArtifact(hasher, artifact)
+			if err := cx.Err(); err != nil {
+				return err
+			}
+
+			sum, err := checksumArtifact(checksum.Algorithm.Hash, artifact)
 			if err != nil {
 				return err
 			}
@@ -74,51 +114,82 @@ func (checksum *Checksum) Run(cx context.Context) error {
 		}
 	}
 
-	writer, err := os.Create(checksumFilename)
+	if err := writeChecksums(checksumFilename, checksums); err != nil {
+		_ = os.Remove(checksumFilename)
+		return err
+	}
+
+	if err := artifact.Stat(); err != nil {
+		return err
+	}
+
+	context.AddArtifact(artifact)
+
+	log.Printf("checksum file %s written", checksumFilename)
+
+	return nil
+}
+
+// writeChecksums writes checksum lines into a file
+func writeChecksums(filename string, checksums []string) error {
+	writer, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("opening checksum file for writing: %w", err)
 	}
 
-	defer writer.Close()
-
 	for _, line := range checksums {
 		if _, err := fmt.Fprintln(writer, line); err != nil {
+			writer.Close()
 			return fmt.Errorf("writing checksum file: %w", err)
 		}
 	}
 
-	context.Artifacts.Add(&ctx.Artifact{
-		Filename: output,
-		Location: checksumFilename,
-		ID:       checksum.ID,
-	})
-
-	log.Printf("checksum file %s written", checksumFilename)
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("writing checksum file: %w", err)
+	}
 
 	return nil
 }
 
-func checksumArtifact(hasher hash.Hash, artifact *ctx.Artifact) (string, error) {
-	hasher.Reset()
+// Plan describes the checksum file to be written, and predicts its artifact
+func (checksum *Checksum) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
 
-	f, err := os.Open(artifact.Location)
+	output, err := checksum.parseOutput(cx)
 	if err != nil {
-		return "", fmt.Errorf("checksumming %s: %w", artifact.Location, err)
+		return fmt.Errorf("generating checksum filename: %w", err)
 	}
 
-	if _, err := io.Copy(hasher, f); err != nil {
-		return "", fmt.Errorf("reading %s for checksumming: %w", artifact.Location, err)
+	if len(context.ListArtifacts().OsArchBySelector(checksum.Builds.Skipping(checksum.Skip))) == 0 {
+		return nil
 	}
 
-	if err := f.Close(); err != nil {
-		return "", fmt.Errorf("closing %s after checksumming: %w", artifact.Location, err)
+	artifact := checksum.artifact(context.TargetDir, output)
+	plan.AddFile(artifact.Location)
+
+	return plan.AddArtifact(cx, artifact)
+}
+
+func (checksum *Checksum) artifact(targetDir, output string) *ctx.Artifact {
+	return &ctx.Artifact{
+		Filename: output,
+		Location: path.Join(targetDir, output),
+		ID:       checksum.ID,
+		Kind:     ctx.KindChecksum,
+		Extra:    map[string]interface{}{"algorithm": checksum.Algorithm.String()},
+	}
+}
+
+func checksumArtifact(hash crypto.Hash, artifact *ctx.Artifact) (string, error) {
+	digest, err := artifact.Digest(hash)
+	if err != nil {
+		return "", fmt.Errorf("checksumming %s: %w", artifact.Location, err)
 	}
 
-	return fmt.Sprintf(
-		"%x  %s",
-		hasher.Sum(nil),
-		artifact.Filename,
-	), nil
+	return fmt.Sprintf("%s  %s", digest, artifact.Filename), nil
 }
 
 func (checksum *Checksum) parseOutput(cx context.Context) (string, error) {
@@ -5,51 +5,112 @@ import (
 	"fmt"
 	"io"
 
-	"gopkg.in/yaml.v3"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 type (
 	// Compressor defines compression interface
-	Compressor interface {
-		fmt.Stringer
-		Extension() string
-		Writer(io.Writer) io.WriteCloser
-	}
+	Compressor = modules.Compressor
 
 	// Compression is a YAML representation of a compression format
-	Compression struct {
-		Compressor
-	}
+	Compression = modules.Compression
 
 	// CompressNONE defines a flowthrough compression
 	CompressNONE struct{}
 
 	// CompressGz defines a gzip compression
-	CompressGz struct{}
+	CompressGz struct {
+		// Level is the gzip compression level, from 1 to 9. Level 0 is
+		// gzip's default level.
+		Level int
+	}
+
+	// CompressXz defines an xz compression
+	CompressXz struct {
+		// Level is the xz preset level, from 1 to 9. It selects only the
+		// dictionary size of the preset, other preset settings are not
+		// supported. Level 0 is xz's default level, 6.
+		Level int
+	}
+
+	// CompressZstd defines a zstandard compression
+	CompressZstd struct {
+		// Level is the zstd compression level, from 1 to 22. Level 0 is
+		// zstd's default level, 3.
+		Level int
+	}
 
 	nopWriteCloser struct {
 		io.Writer
 	}
-)
 
-// UnmarshalYAML detects compression format
-func (c *Compression) UnmarshalYAML(node *yaml.Node) error {
-	if node.Kind != yaml.ScalarNode {
-		return fmt.Errorf("compression is `%v`, not scalar", node.Kind)
+	// failedWriter is a writer of a compressor, which cannot be set up.
+	// It returns the setup error on every call.
+	failedWriter struct {
+		err error
 	}
+)
 
-	var compressString string
-	if err := node.Decode(&compressString); err != nil {
-		return fmt.Errorf("compression cannot be decoded: %w", err)
-	}
+// nolint: gochecknoglobals
+var (
+	// xzDictCaps are dictionary sizes of xz preset levels
+	xzDictCaps = []int{1 << 18, 1 << 20, 1 << 21, 1 << 22, 1 << 22, 1 << 23, 1 << 23, 1 << 24, 1 << 25, 1 << 26}
+)
+
+// nolint: gochecknoinits
+func init() {
+	modules.RegisterCompressor(&modules.CompressorRegistration{
+		Name:    "none",
+		Aliases: []string{"", "NONE"},
+		Factory: func(level int) (Compressor, error) {
+			if level != 0 {
+				return nil, fmt.Errorf("compression levels are not supported without compression")
+			}
+
+			return &CompressNONE{}, nil
+		},
+	})
+	modules.RegisterCompressor(&modules.CompressorRegistration{
+		Name:    "gzip",
+		Aliases: []string{"gz", "GZip"},
+		Factory: func(level int) (Compressor, error) {
+			if err := checkLevel("gzip", level, 9); err != nil {
+				return nil, err
+			}
+
+			return &CompressGz{Level: level}, nil
+		},
+	})
+	modules.RegisterCompressor(&modules.CompressorRegistration{
+		Name: "xz",
+		Factory: func(level int) (Compressor, error) {
+			if err := checkLevel("xz", level, 9); err != nil {
+				return nil, err
+			}
+
+			return &CompressXz{Level: level}, nil
+		},
+	})
+	modules.RegisterCompressor(&modules.CompressorRegistration{
+		Name:    "zstd",
+		Aliases: []string{"zst"},
+		Factory: func(level int) (Compressor, error) {
+			if err := checkLevel("zstd", level, 22); err != nil {
+				return nil, err
+			}
+
+			return &CompressZstd{Level: level}, nil
+		},
+	})
+}
 
-	switch compressString {
-	case "", "none", "NONE":
-		(*c) = Compression{&CompressNONE{}}
-	case "gz", "gzip", "GZip":
-		(*c) = Compression{&CompressGz{}}
-	default:
-		return fmt.Errorf("invalid compression format: `%s`", compressString)
+// checkLevel checks whether a compression level is between 1, and max,
+// or 0 for the default level
+func checkLevel(format string, level, max int) error {
+	if level < 0 || level > max {
+		return fmt.Errorf("invalid %s level %d, valid values are 1 to %d", format, level, max)
 	}
 
 	return nil
@@ -76,9 +137,87 @@ func (c *CompressGz) Extension() string {
 }
 
 func (c *CompressGz) Writer(writer io.Writer) io.WriteCloser {
-	return gzip.NewWriter(writer)
+	return writerOf(c.NewWriter(writer))
+}
+
+func (c *CompressGz) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	gz, err := gzip.NewWriterLevel(writer, level)
+	if err != nil {
+		return nil, err
+	}
+
+	// deterministic header: no file name, modification time, or OS
+	gz.Header = gzip.Header{OS: 255}
+
+	return gz, nil
+}
+
+func (c *CompressXz) String() string {
+	return "xz"
+}
+
+func (c *CompressXz) Extension() string {
+	return ".xz"
+}
+
+func (c *CompressXz) Writer(writer io.Writer) io.WriteCloser {
+	return writerOf(c.NewWriter(writer))
+}
+
+func (c *CompressXz) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = 6
+	}
+
+	config := xz.WriterConfig{DictCap: xzDictCaps[level]}
+
+	return config.NewWriter(writer)
+}
+
+func (c *CompressZstd) String() string {
+	return "zstd"
+}
+
+func (c *CompressZstd) Extension() string {
+	return ".zst"
+}
+
+func (c *CompressZstd) Writer(writer io.Writer) io.WriteCloser {
+	return writerOf(c.NewWriter(writer))
+}
+
+func (c *CompressZstd) NewWriter(writer io.Writer) (io.WriteCloser, error) {
+	level := zstd.SpeedDefault
+	if c.Level != 0 {
+		level = zstd.EncoderLevelFromZstd(c.Level)
+	}
+
+	return zstd.NewWriter(writer, zstd.WithEncoderLevel(level))
 }
 
 func (nopWriteCloser) Close() error {
 	return nil
 }
+
+// writerOf returns writer, or a writer failing with err, if it is set
+func writerOf(writer io.WriteCloser, err error) io.WriteCloser {
+	if err != nil {
+		return &failedWriter{err: err}
+	}
+
+	return writer
+}
+
+func (w *failedWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func (w *failedWriter) Close() error {
+	return w.err
+}
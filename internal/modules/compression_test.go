@@ -0,0 +1,167 @@
+package modules
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCompression_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		ext     string
+		level   int
+		errStr  string
+	}{
+		{name: "default", content: `""`, want: "none\n"},
+		{name: "alias", content: `gz`, want: "gzip\n", ext: ".gz"},
+		{name: "xz", content: `xz`, want: "xz\n", ext: ".xz"},
+		{name: "mapping", content: `{format: zstd, level: 19}`, want: "format: zstd\nlevel: 19\n", ext: ".zst", level: 19},
+		{name: "invalid level", content: `{format: gzip, level: 10}`, errStr: "line 1, column 1: invalid gzip level 10, valid values are 1 to 9"},
+		{name: "unknown field", content: `{format: gzip, speed: 1}`, errStr: `line 1, column 16: unknown compression field "speed", expecting format, or level`},
+		{name: "unknown format", content: `bzip2`, errStr: "line 1, column 1: invalid compression format: `bzip2`"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var compression Compression
+
+			err := yaml.Unmarshal([]byte(tt.content), &compression)
+			if tt.errStr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errStr) {
+					t.Errorf("Compression.UnmarshalYAML() error = %v, want %q", err, tt.errStr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Compression.UnmarshalYAML() unexpected error: %v", err)
+			}
+
+			if compression.Extension() != tt.ext || compression.Level != tt.level {
+				t.Errorf("Compression.UnmarshalYAML() = %s level %d, want %s level %d", compression.Extension(), compression.Level, tt.ext, tt.level)
+			}
+
+			out, err := yaml.Marshal(compression)
+			if err != nil {
+				t.Fatalf("Compression.MarshalYAML() unexpected error: %v", err)
+			}
+
+			if string(out) != tt.want {
+				t.Errorf("Compression.MarshalYAML() = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressors(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"xz":   func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	content := bytes.Repeat([]byte("goshipdone "), 1000)
+
+	for format, reader := range readers {
+		for _, level := range []int{0, 1, 9} {
+			compression, err := modules.NewCompression(format, level)
+			if err != nil {
+				t.Fatalf("%s %d: NewCompression() unexpected error: %v", format, level, err)
+			}
+
+			var buf bytes.Buffer
+
+			writer, err := compression.NewWriter(&buf)
+			if err != nil {
+				t.Fatalf("%s %d: NewWriter() unexpected error: %v", format, level, err)
+			}
+
+			if _, err := writer.Write(content); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			decompressor, err := reader(&buf)
+			if err != nil {
+				t.Fatalf("%s %d: cannot read compressed data: %v", format, level, err)
+			}
+
+			got, err := ioutil.ReadAll(decompressor)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Errorf("%s %d: decompressed %d bytes, want %d, error: %v", format, level, len(got), len(content), err)
+			}
+		}
+	}
+}
+
+type testCompressor struct{}
+
+func (testCompressor) String() string    { return "test" }
+func (testCompressor) Extension() string { return ".test" }
+
+func (testCompressor) Writer(w io.Writer) io.WriteCloser {
+	return &nopWriteCloser{Writer: w}
+}
+
+type testCheckedCompressor struct {
+	testCompressor
+}
+
+func (testCheckedCompressor) NewWriter(io.Writer) (io.WriteCloser, error) {
+	return nil, errors.New("cannot set up")
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	modules.RegisterCompressor(&modules.CompressorRegistration{
+		Name: "test",
+		Factory: func(int) (modules.Compressor, error) {
+			return testCompressor{}, nil
+		},
+	})
+
+	var compression Compression
+	if err := yaml.Unmarshal([]byte(`test`), &compression); err != nil {
+		t.Fatalf("Compression.UnmarshalYAML() unexpected error: %v", err)
+	}
+
+	if compression.Extension() != ".test" {
+		t.Errorf("Compression.UnmarshalYAML() extension = %q", compression.Extension())
+	}
+}
+
+func TestCompression_NewWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := Compression{Compressor: testCompressor{}}.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("Compression.NewWriter() unexpected error: %v", err)
+	}
+
+	if _, err := writer.Write([]byte("content")); err != nil || buf.String() != "content" {
+		t.Errorf("Compression.NewWriter() wrote %q, error: %v", buf.String(), err)
+	}
+
+	if _, err := (Compression{Compressor: testCheckedCompressor{}}).NewWriter(&buf); err == nil {
+		t.Error("Compression.NewWriter() expected error")
+	}
+
+	if _, err := writerOf(testCheckedCompressor{}.NewWriter(&buf)).Write([]byte("content")); err == nil {
+		t.Error("writerOf() writer expected error")
+	}
+}
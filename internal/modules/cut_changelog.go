@@ -42,6 +42,33 @@ func NewCutChangelog() modules.Pluggable {
 	}
 }
 
+// Describe documents CutChangelog module
+func (*CutChangelog) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Cuts the current release's section from a CHANGELOG file for release notes",
+		Fields: map[string]string{
+			"id":     "Resulting artifact ID",
+			"input":  "Input CHANGELOG file in keepachangelog.org format",
+			"output": "Output file name in target directory. Default: same as input",
+		},
+	}
+}
+
+// Validate checks changelog settings
+func (mod *CutChangelog) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireField("id", mod.ID))
+	errs.Add(modules.RequireField("input", mod.Input))
+
+	return errs.Err()
+}
+
+// Produces returns the artifact ID of the changelog slice
+func (mod *CutChangelog) Produces() []string {
+	return []string{mod.ID}
+}
+
 func (mod *CutChangelog) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
@@ -79,22 +106,50 @@ func (mod *CutChangelog) Run(cx context.Context) error {
 
 	matches[1] = regexp.MustCompile(reDuplicateLinks).ReplaceAll(matches[1], []byte("$1"))
 
-	outfile := mod.Output
-	if outfile == "" {
-		outfile = mod.Input
-	}
-
-	outfile = path.Join(context.TargetDir, outfile)
+	outfile := mod.outputFile(context)
 
 	if err := os.WriteFile(outfile, matches[1], 0o644); err != nil { // nolint: gosec
 		return fmt.Errorf("writing sliced CHANGELOG %s: %w", outfile, err)
 	}
 
-	context.Artifacts.Add(&ctx.Artifact{
+	artifact := mod.artifact(outfile)
+	if err := artifact.Stat(); err != nil {
+		return err
+	}
+
+	context.AddArtifact(artifact)
+
+	return nil
+}
+
+// Plan describes the changelog slice to be written, and predicts its artifact
+func (mod *CutChangelog) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	outfile := mod.outputFile(context)
+	plan.AddFile(outfile)
+
+	return plan.AddArtifact(cx, mod.artifact(outfile))
+}
+
+// artifact returns the release notes artifact the module creates
+func (mod *CutChangelog) artifact(outfile string) *ctx.Artifact {
+	return &ctx.Artifact{
 		ID:       mod.ID,
 		Filename: mod.Input,
+		Kind:     ctx.KindReleaseNotes,
 		Location: outfile,
-	})
+	}
+}
 
-	return nil
+func (mod *CutChangelog) outputFile(context *ctx.Context) string {
+	outfile := mod.Output
+	if outfile == "" {
+		outfile = mod.Input
+	}
+
+	return path.Join(context.TargetDir, outfile)
 }
@@ -22,6 +22,18 @@ func NewEnv() modules.Pluggable {
 	return &Env{}
 }
 
+// Describe documents Env module
+func (*Env) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Loads environment variables into the build context",
+	}
+}
+
+// Plan loads environment variables, as it has no side effects
+func (mod *Env) Plan(cx context.Context, _ *modules.ModulePlan) error {
+	return mod.Run(cx)
+}
+
 func (*Env) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
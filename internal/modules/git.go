@@ -3,10 +3,10 @@ package modules
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // Git is a module, which takes a git repo, and filling in
@@ -18,6 +18,18 @@ func NewGit() modules.Pluggable {
 	return &Git{}
 }
 
+// Describe documents Git module
+func (*Git) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Records git version, current tag, current ref, commit time, and remote URL",
+	}
+}
+
+// Plan records git tag information, as it has no side effects
+func (mod *Git) Plan(cx context.Context, _ *modules.ModulePlan) error {
+	return mod.Run(cx)
+}
+
 // Run records git tag information into ctx.Context
 func (*Git) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
@@ -25,6 +37,8 @@ func (*Git) Run(cx context.Context) error {
 		return err
 	}
 
+	var timestamp string
+
 	items := []struct {
 		name     string
 		required bool
@@ -35,10 +49,11 @@ func (*Git) Run(cx context.Context) error {
 		{"current tag", false, &context.Git.Tag, []string{"describe", "--exact-match", "--tags"}},
 		{"current ref", true, &context.Git.Ref, []string{"-P", "show", "--format=%H", "-s"}},
 		{"url", false, &context.Git.URL, []string{"ls-remote", "--get-url"}},
+		{"commit time", false, &timestamp, []string{"-P", "show", "--format=%ct", "-s"}},
 	}
 
 	for _, item := range items {
-		val, err := sh.Output("git", item.args...)
+		val, err := modules.CommandOutput(cx, append([]string{"git"}, item.args...)...)
 		if item.required && err != nil {
 			return fmt.Errorf("cannot detect %s from git: %w", item.name, err)
 		}
@@ -46,5 +61,11 @@ func (*Git) Run(cx context.Context) error {
 		*item.target = val
 	}
 
+	// commit time is left unknown if it cannot be detected, modules
+	// requiring it report it
+	if commitTimestamp, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		context.Git.CommitTimestamp = commitTimestamp
+	}
+
 	return nil
 }
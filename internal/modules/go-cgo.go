@@ -0,0 +1,220 @@
+package modules
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// GoCgo configures C toolchains of cgo builds
+	GoCgo struct {
+		// Resolver is the name of a registered C toolchain resolver,
+		// like `zig`, providing toolchains of all targets.
+		Resolver string
+		// Targets maps OS-arch names, or patterns, like linux-amd64,
+		// or linux-*, to C toolchains. Their fields are
+		// `modules.TemplateData` templates, and they override the
+		// resolver's toolchains. Patterns are applied before exact
+		// names, in alphabetical order.
+		Targets map[string]*modules.CToolchain
+	}
+
+	// zigResolver provides C toolchains of `zig cc`, linking against
+	// musl on linux
+	zigResolver struct{}
+)
+
+// nolint: gochecknoglobals
+var (
+	zigArchs = map[string]string{
+		"386":      "x86",
+		"amd64":    "x86_64",
+		"arm":      "arm",
+		"arm64":    "aarch64",
+		"loong64":  "loongarch64",
+		"mips":     "mips",
+		"mipsle":   "mipsel",
+		"mips64":   "mips64",
+		"mips64le": "mips64el",
+		"ppc64le":  "powerpc64le",
+		"riscv64":  "riscv64",
+		"s390x":    "s390x",
+	}
+	zigOSes = map[string]string{
+		"darwin":  "macos",
+		"freebsd": "freebsd",
+		"linux":   "linux",
+		"netbsd":  "netbsd",
+		"windows": "windows",
+	}
+)
+
+// nolint: gochecknoinits
+func init() {
+	modules.RegisterCToolchainResolver("zig", &zigResolver{})
+}
+
+// Validate checks cgo settings
+func (cgo *GoCgo) Validate() error {
+	if cgo == nil {
+		return nil
+	}
+
+	var errs modules.Errors
+
+	if cgo.Resolver != "" {
+		if _, ok := modules.LookupCToolchainResolver(cgo.Resolver); !ok {
+			errs.Add(modules.NewFieldError(
+				"cgo.resolver",
+				"unknown resolver %q, valid values are %s",
+				cgo.Resolver,
+				strings.Join(modules.CToolchainResolvers(), ", "),
+			))
+		}
+	}
+
+	for _, key := range cgo.keys() {
+		errs.Extend(modules.Filters{{OsArch: key}}.Validate("cgo.targets"))
+	}
+
+	return errs.Err()
+}
+
+// Toolchain returns the C toolchain of a target, from the resolver,
+// overridden by matching entries of Targets. It returns nil if cgo is not
+// configured.
+func (cgo *GoCgo) Toolchain(osarch *ctx.OsArch) (*modules.CToolchain, error) {
+	if cgo == nil {
+		return nil, nil
+	}
+
+	toolchain := modules.CToolchain{}
+
+	if cgo.Resolver != "" {
+		resolver, ok := modules.LookupCToolchainResolver(cgo.Resolver)
+		if !ok {
+			return nil, fmt.Errorf("unknown C toolchain resolver %q", cgo.Resolver)
+		}
+
+		resolved, err := resolver.Resolve(osarch)
+		if err != nil {
+			return nil, err
+		}
+
+		toolchain = toolchain.Override(resolved)
+	}
+
+	art := &ctx.Artifact{OsArch: osarch}
+
+	for _, key := range cgo.keys() {
+		entry := cgo.Targets[key]
+		if entry != nil && (&ctx.ArtifactFilter{OsArch: key}).Match(art) {
+			toolchain = toolchain.Override(entry)
+		}
+	}
+
+	return &toolchain, nil
+}
+
+// keys returns keys of Targets, patterns first, in alphabetical order
+func (cgo *GoCgo) keys() []string {
+	keys := make([]string, 0, len(cgo.Targets))
+
+	for key := range cgo.Targets {
+		keys = append(keys, key)
+	}
+
+	sortPatternsFirst(keys)
+
+	return keys
+}
+
+// isPattern checks whether a string is a glob pattern
+func isPattern(text string) bool {
+	return strings.ContainsAny(text, "*?[")
+}
+
+// sortPatternsFirst sorts patterns before exact names, in alphabetical
+// order, so exact names can override patterns when applied in order
+func sortPatternsFirst(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		iPattern, jPattern := isPattern(keys[i]), isPattern(keys[j])
+		if iPattern != jPattern {
+			return iPattern
+		}
+
+		return keys[i] < keys[j]
+	})
+}
+
+// checkCToolchains checks whether commands of C toolchains of targets are
+// available. Each command is checked only once.
+func checkCToolchains(targets []*goSingleTarget) error {
+	var errs modules.Errors
+
+	checked := map[string]bool{}
+
+	for _, target := range targets {
+		if target.cgo == nil {
+			continue
+		}
+
+		commands := target.cgo.Commands()
+		keys := make([]string, 0, len(commands))
+
+		for key := range commands {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fields := strings.Fields(commands[key])
+			if len(fields) == 0 || checked[fields[0]] {
+				continue
+			}
+
+			checked[fields[0]] = true
+
+			if _, err := exec.LookPath(fields[0]); err != nil {
+				errs.Add(fmt.Errorf("cgo: %s of %s: %w", key, target.OSArch(), err))
+			}
+		}
+	}
+
+	return errs.Err()
+}
+
+// Resolve returns `zig cc`, `zig c++`, and `zig ar` commands of a target
+func (*zigResolver) Resolve(osarch *ctx.OsArch) (*modules.CToolchain, error) {
+	arch, archOK := zigArchs[osarch.Arch]
+	goos, osOK := zigOSes[osarch.OS]
+
+	if !archOK || !osOK {
+		return nil, fmt.Errorf("zig: unsupported target %s/%s", osarch.OS, osarch.Arch)
+	}
+
+	target := arch + "-" + goos
+
+	switch osarch.OS {
+	case "linux":
+		target += "-musl"
+
+		if osarch.Arch == "arm" {
+			target += map[bool]string{true: "eabi", false: "eabihf"}[osarch.Variant == "5"]
+		}
+	case "windows":
+		target += "-gnu"
+	}
+
+	return &modules.CToolchain{
+		AR:  "zig ar",
+		CC:  "zig cc -target " + target,
+		CXX: "zig c++ -target " + target,
+	}, nil
+}
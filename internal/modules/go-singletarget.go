@@ -2,11 +2,16 @@ package modules
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -16,23 +21,26 @@ import (
 var ErrSkippedTarget = errors.New("target is skipped")
 
 type goSingleTarget struct {
-	mod     *Go
-	Env     *withenv.Env
-	ID      string
-	LDFlags string
-	OutDir  string
-	Main    string
-	osarch  *ctx.OsArch
-	Output  string
+	mod          *Go
+	cgo          *modules.CToolchain
+	Env          *withenv.Env
+	flags        GoFlags
+	ID           string
+	ModTimestamp string
+	OutDir       string
+	Main         string
+	osarch       *ctx.OsArch
+	Output       string
+	sourceDate   string
 }
 
-func (mod *Go) newSingleTarget(goos, goarch string, goarm int32) *goSingleTarget {
+func (mod *Go) newSingleTarget(osarch *ctx.OsArch) *goSingleTarget {
 	return &goSingleTarget{
 		mod:    mod,
 		Env:    withenv.New(),
 		ID:     mod.ID,
 		Main:   mod.Main,
-		osarch: &ctx.OsArch{OS: goos, Arch: goarch, ArmVersion: goarm},
+		osarch: osarch,
 	}
 }
 
@@ -46,51 +54,255 @@ func (tar *goSingleTarget) Setup(cx context.Context) error {
 		tar.Env.Set(key, val)
 	}
 
-	tar.SetGoEnv()
+	if tar.mod.SourceDateEpoch {
+		if _, ok := tar.Env.Get(ctx.SourceDateEpochVar); !ok {
+			if context.Git.CommitTimestamp == 0 {
+				return fmt.Errorf("cannot set %s: commit time is unknown", ctx.SourceDateEpochVar)
+			}
 
-	osarch := tar.OSArch()
-	for _, skip := range tar.mod.Skip {
-		if osarch == skip {
-			return ErrSkippedTarget
+			tar.sourceDate = strconv.FormatInt(context.Git.CommitTimestamp, 10)
 		}
 	}
 
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return err
+	}
+
+	td.OSArch = tar.osarch
+
+	if err := tar.renderFlags(td); err != nil {
+		return err
+	}
+
+	if err := tar.renderCgo(td); err != nil {
+		return err
+	}
+
+	tar.SetGoEnv()
+
+	td.Ext = buildModeExt(tar.flags.BuildMode, tar.osarch.OS)
+
 	tasks := []struct {
 		name   string
 		source string
 		target *string
 	}{
-		{"ldflags", tar.mod.LDFlags, &tar.LDFlags},
 		{"location", path.Join(
 			context.TargetDir,
 			"{{.ProjectName}}-{{OS}}-{{ArchName}}"), &tar.OutDir},
 		{"output", tar.mod.Output, &tar.Output},
+		{"mod_timestamp", tar.mod.ModTimestamp, &tar.ModTimestamp},
 	}
 
-	td, err := modules.NewTemplate(cx)
-	if err != nil {
-		return err
+	for _, item := range tasks {
+		(*item.target), err = td.Parse("build:go", item.source)
+		if err != nil {
+			return fmt.Errorf("cannot render %s: %w", item.name, err)
+		}
 	}
 
-	td.OSArch = tar.osarch
+	if tar.mod.Skip.Match(tar.artifact()) {
+		return ErrSkippedTarget
+	}
+
+	if tar.ModTimestamp != "" {
+		if _, err := strconv.ParseInt(tar.ModTimestamp, 10, 64); err != nil {
+			return fmt.Errorf("invalid mod_timestamp %q: must be a Unix timestamp", tar.ModTimestamp)
+		}
+	}
+
+	return nil
+}
+
+// renderFlags renders build flags of the target, overridden by matching
+// overrides
+func (tar *goSingleTarget) renderFlags(td *modules.TemplateData) error {
+	flags := tar.mod.GoFlags
+	match := &ctx.Artifact{ID: tar.ID, Kind: ctx.KindBinary, OsArch: tar.osarch}
+
+	for _, override := range tar.mod.Overrides {
+		if override.Match.Match(match) {
+			flags = flags.override(&override.GoFlags)
+		}
+	}
+
+	tasks := []struct {
+		name   string
+		target *string
+	}{
+		{"asmflags", &flags.ASMFlags},
+		{"buildmode", &flags.BuildMode},
+		{"gcflags", &flags.GCFlags},
+		{"ldflags", &flags.LDFlags},
+		{"mod", &flags.Mod},
+	}
+
+	var err error
 
 	for _, item := range tasks {
-		(*item.target), err = td.Parse("build:go", item.source)
+		(*item.target), err = td.Parse("build:go", *item.target)
 		if err != nil {
 			return fmt.Errorf("cannot render %s: %w", item.name, err)
 		}
 	}
 
+	lists := []struct {
+		name   string
+		target *[]string
+	}{
+		{"env", &flags.Env},
+		{"tags", &flags.Tags},
+	}
+
+	for _, list := range lists {
+		rendered := make([]string, 0, len(*list.target))
+
+		for _, item := range *list.target {
+			value, err := td.Parse("build:go", item)
+			if err != nil {
+				return fmt.Errorf("cannot render %s: %w", list.name, err)
+			}
+
+			rendered = append(rendered, value)
+		}
+
+		*list.target = rendered
+	}
+
+	if err := flags.validate(""); err != nil {
+		return err
+	}
+
+	tar.flags = flags
+
+	return nil
+}
+
+// renderCgo renders the C toolchain of the target
+func (tar *goSingleTarget) renderCgo(td *modules.TemplateData) error {
+	toolchain, err := tar.mod.Cgo.Toolchain(tar.osarch)
+	if err != nil || toolchain == nil {
+		return err
+	}
+
+	for _, item := range []struct {
+		name   string
+		target *string
+	}{
+		{"ar", &toolchain.AR},
+		{"cc", &toolchain.CC},
+		{"cxx", &toolchain.CXX},
+		{"cflags", &toolchain.CFlags},
+		{"ldflags", &toolchain.LDFlags},
+	} {
+		(*item.target), err = td.Parse("build:go", *item.target)
+		if err != nil {
+			return fmt.Errorf("cannot render cgo %s: %w", item.name, err)
+		}
+	}
+
+	if !toolchain.IsEmpty() {
+		tar.cgo = toolchain
+	}
+
 	return nil
 }
 
 func (tar *goSingleTarget) SetGoEnv() {
-	tar.Env.Set("GOOS", tar.osarch.OS)
-	tar.Env.Set("GOARCH", tar.osarch.Arch)
+	for _, item := range tar.goEnv() {
+		keyval := strings.SplitN(item, "=", 2)
+		tar.Env.Set(keyval[0], keyval[1])
+	}
+}
+
+// goEnv returns go-specific environment variables of the target, in
+// KEY=value format.
+func (tar *goSingleTarget) goEnv() []string {
+	env := []string{
+		"GOOS=" + tar.osarch.OS,
+		"GOARCH=" + tar.osarch.Arch,
+	}
+
+	if tar.osarch.Variant != "" {
+		env = append(env, ctx.VariantVar(tar.osarch.Arch)+"="+tar.osarch.Variant)
+	}
+
+	if tar.mod.CGOEnabled != nil {
+		env = append(env, "CGO_ENABLED="+map[bool]string{true: "1", false: "0"}[*tar.mod.CGOEnabled])
+	} else if tar.cgo != nil {
+		env = append(env, "CGO_ENABLED=1")
+	}
+
+	if tar.sourceDate != "" {
+		env = append(env, ctx.SourceDateEpochVar+"="+tar.sourceDate)
+	}
+
+	if tar.cgo != nil {
+		env = append(env, tar.cgo.Env()...)
+	}
+
+	return append(env, tar.flags.Env...)
+}
 
-	if tar.osarch.ArmVersion != 0 {
-		tar.Env.Set("GOARM", strconv.Itoa(int(tar.osarch.ArmVersion)))
+// command returns the build command of the target
+func (tar *goSingleTarget) command() []string {
+	return tar.commandTo(path.Join(tar.OutDir, tar.Output))
+}
+
+// commandTo returns the build command of the target, writing its result
+// into output. Extra flags are inserted before the main package.
+func (tar *goSingleTarget) commandTo(output string, extra ...string) []string {
+	cmd := []string{"go", "build", "-o", output}
+
+	if tar.mod.Trimpath {
+		cmd = append(cmd, "-trimpath")
+	}
+
+	if tar.mod.BuildVCS != "" {
+		cmd = append(cmd, "-buildvcs="+tar.mod.BuildVCS)
+	}
+
+	if tar.flags.BuildMode != "" {
+		cmd = append(cmd, "-buildmode="+tar.flags.BuildMode)
+	}
+
+	if tar.flags.Mod != "" {
+		cmd = append(cmd, "-mod="+tar.flags.Mod)
+	}
+
+	if len(tar.flags.Tags) > 0 {
+		cmd = append(cmd, "-tags", strings.Join(tar.flags.Tags, ","))
+	}
+
+	if tar.flags.GCFlags != "" {
+		cmd = append(cmd, "-gcflags", tar.flags.GCFlags)
+	}
+
+	if tar.flags.ASMFlags != "" {
+		cmd = append(cmd, "-asmflags", tar.flags.ASMFlags)
+	}
+
+	cmd = append(cmd, extra...)
+
+	return append(cmd, "-ldflags", tar.flags.LDFlags, tar.Main)
+}
+
+// artifact returns the artifact the target builds
+func (tar *goSingleTarget) artifact() *ctx.Artifact {
+	artifact := &ctx.Artifact{
+		Filename: tar.Output,
+		Location: path.Join(tar.OutDir, tar.Output),
+		ID:       tar.ID,
+		Kind:     ctx.KindBinary,
+		OsArch:   tar.osarch,
+	}
+
+	if tar.flags.BuildMode != "" {
+		artifact.Extra = map[string]interface{}{"buildmode": tar.flags.BuildMode}
 	}
+
+	return artifact
 }
 
 func (tar *goSingleTarget) OSArch() string {
@@ -107,19 +319,127 @@ func (tar *goSingleTarget) Run(cx context.Context) error {
 		return err
 	}
 
-	output := path.Join(tar.OutDir, tar.Output)
-
-	if err := tar.Env.Run("go", "build", "-o", output, "-ldflags", tar.LDFlags, tar.Main); err != nil {
-		_ = os.Remove(output)
+	artifact, err := tar.build(cx)
+	if err != nil {
 		return err
 	}
 
-	context.Artifacts.Add(&ctx.Artifact{
-		Filename: tar.Output,
-		Location: output,
-		ID:       tar.ID,
-		OsArch:   tar.osarch,
-	})
+	context.AddArtifact(artifact)
 
 	return nil
 }
+
+// build runs `go build` for the target, and returns the resulting artifact
+// without registering it.
+func (tar *goSingleTarget) build(cx context.Context) (*ctx.Artifact, error) {
+	artifact := tar.artifact()
+
+	if tar.mod.Verify {
+		if err := tar.verifiedBuild(cx, artifact.Location); err != nil {
+			_ = os.Remove(artifact.Location)
+			return nil, err
+		}
+	} else if err := modules.RunCommand(cx, tar.Env, tar.command()...); err != nil {
+		_ = os.Remove(artifact.Location)
+		return nil, err
+	}
+
+	if tar.ModTimestamp != "" {
+		epoch, _ := strconv.ParseInt(tar.ModTimestamp, 10, 64)
+		modTime := time.Unix(epoch, 0)
+
+		if err := os.Chtimes(artifact.Location, modTime, modTime); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := artifact.Stat(); err != nil {
+		return nil, err
+	}
+
+	return artifact, nil
+}
+
+// verifyCommands returns build commands of reproducibility verification,
+// writing into separate directories. They rebuild all packages, to avoid
+// reusing build results from the build cache.
+func (tar *goSingleTarget) verifyCommands(dirs ...string) [][]string {
+	commands := make([][]string, 0, len(dirs))
+
+	for _, dir := range dirs {
+		commands = append(commands, tar.commandTo(path.Join(dir, tar.Output), "-a"))
+	}
+
+	return commands
+}
+
+// verifiedBuild builds the target twice, in separate temporary
+// directories, and it copies the result to location, if both builds are
+// identical.
+func (tar *goSingleTarget) verifiedBuild(cx context.Context, location string) error {
+	dirs := make([]string, 2)
+
+	for idx := range dirs {
+		dir, err := ioutil.TempDir("", "goshipdone-verify-")
+		if err != nil {
+			return err
+		}
+
+		defer os.RemoveAll(dir)
+
+		dirs[idx] = dir
+	}
+
+	digests := make([]string, len(dirs))
+
+	for idx, command := range tar.verifyCommands(dirs...) {
+		if err := modules.RunCommand(cx, tar.Env, command...); err != nil {
+			return err
+		}
+
+		build := &ctx.Artifact{Filename: tar.Output, Location: path.Join(dirs[idx], tar.Output)}
+
+		digest, err := build.Digest(crypto.SHA256)
+		if err != nil {
+			return err
+		}
+
+		digests[idx] = digest
+	}
+
+	if digests[0] != digests[1] {
+		return fmt.Errorf("build is not reproducible: sha256 digests %s and %s differ", digests[0], digests[1])
+	}
+
+	return copyFile(path.Join(dirs[0], tar.Output), location)
+}
+
+// copyFile copies a file with its permissions, creating its directory
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -3,7 +3,11 @@ package modules
 import (
 	"context"
 	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -11,40 +15,64 @@ import (
 
 // Go represents build:go module
 type Go struct {
+	// GoFlags are build flags of all targets
+	GoFlags `yaml:",inline"`
 	// After is a list of commands have to be ran after builds.
 	// Any errors cancel the task.
 	After []string
 	// Before is a list of commands have to be ran before builds.
 	// Any errors cancel the task.
 	Before []string
+	// BuildVCS sets `-buildvcs` flag of `go build`: true, false, or auto.
+	// It is not set by default.
+	BuildVCS string `yaml:"buildvcs"`
+	// Cgo configures C toolchains of cgo builds. Targets with a C
+	// toolchain are built with CGO_ENABLED=1, unless CGOEnabled is set.
+	Cgo *GoCgo `yaml:"cgo"`
+	// CGOEnabled sets CGO_ENABLED environment variable of builds, if set.
+	// Reproducible builds should disable cgo.
+	CGOEnabled *bool `yaml:"cgo_enabled"`
 	// GOOS is a list of all GOOS variations required. It is
-	// set to [`windows`, `linux`] by default.
+	// set to [`windows`, `linux`] by default. It is ignored if Targets
+	// are set.
 	GOOS []string
 	// GOArch is a list of all GOARCH variations required. It is
 	// set to [`amd64`] by default.
 	GOArch []string
 	// GOArm is a list of all GOARM variations required. GOARM=6 is
 	// used by default, as golang's internal default. Providing multiple
-	// GOArm entries provides multiple builds while in GOOS=linux and
-	// GOARCH=arm setting.
+	// GOArm entries provides multiple builds for GOARCH=arm.
 	GOArm []int32
 	// ID contains the artifact's name used by later stages of the build
 	// pipeline. Archives, and Publishes may refer to this name for
 	// referencing build results.
 	// Default: "default".
 	ID string
-	// LDFlags is a `modules.TemplateData` template for providing
-	// `-ldflags` configuration option to `go build` command.
-	// It defaults to `-s -w -X main.version={{.Version}}`.
-	LDFlags string
 	// Main designates the file / directory where `main` package
 	// (as well as `main` function) is defined.
 	Main string
+	// ModTimestamp is a `modules.TemplateData` template of the Unix
+	// timestamp the modification time of build results is set to, like
+	// `{{.Git.CommitTimestamp}}`. Modification times are kept if empty.
+	ModTimestamp string `yaml:"mod_timestamp"`
 	// Output is where the build writes its output. Default:
-	// `{{.ProjectName}}{{.Ext}}`
+	// `{{.ProjectName}}{{.Ext}}`, where `{{.Ext}}` is the extension of
+	// the build mode's results on the target OS, like `.exe`, `.so`,
+	// `.dll`, `.dylib`, or `.a`.
 	Output string
+	// Overrides are build flags of targets matching their filters. They
+	// are applied in order, after the module's own flags.
+	Overrides []*GoOverride
+	// Parallelism limits the number of targets built concurrently.
+	// Default: GOMAXPROCS.
+	Parallelism int
+	// SourceDateEpoch sets SOURCE_DATE_EPOCH environment variable of
+	// builds to the commit time of the current commit, unless it is
+	// already set.
+	SourceDateEpoch bool `yaml:"source_date_epoch"`
 	// Skip specifies GOOS-GOArch combinations to be skipped.
-	// They are in `{{.Os}}-{{.Arch}}` format.
+	// They are in `{{.Os}}-{{.Arch}}` format, or artifact filters,
+	// matching the artifact of the target.
 	//
 	// Eg.
 	//
@@ -52,12 +80,148 @@ type Go struct {
 	// Go{
 	//     GOOS: []string{"linux", "windows"},
 	//     GOArch: []string{"amd64", "386"},
-	//     Skip: []string{"linux-386"},
+	//     Skip: modules.Skip{{OsArch: "linux-386"}},
 	// }
 	// ```
 	//
 	// will run builds for linux-amd64, windows-amd64, and windows-386 only.
-	Skip []string
+	Skip modules.Skip
+	// Targets is a list of platforms to build for, in `os_arch`, or
+	// `os_arch_variant` format, like `linux_amd64_v3`, `linux_arm_7`, or
+	// `darwin_arm64`. Variants are values of the architecture's variant
+	// variable, like GOAMD64, GOARM, GOARM64, GO386, or GOMIPS. When set,
+	// GOOS, GOArch, and GOArm are ignored.
+	Targets []string
+	// Trimpath removes file system paths from build results, with
+	// `-trimpath` flag of `go build`.
+	Trimpath bool
+	// Verify builds each target twice, in separate temporary directories,
+	// rebuilding all packages, and fails if the results differ.
+	Verify bool
+}
+
+// GoFlags are build flags of build:go module. All of them are
+// `modules.TemplateData` templates.
+type GoFlags struct {
+	// ASMFlags provides `-asmflags` option to `go build` command.
+	ASMFlags string `yaml:"asmflags"`
+	// BuildMode provides `-buildmode` option to `go build` command,
+	// like `pie`, `c-shared`, or `c-archive`.
+	BuildMode string `yaml:"buildmode"`
+	// Env is a list of extra environment variables of builds, in
+	// `KEY=value` format.
+	Env []string
+	// GCFlags provides `-gcflags` option to `go build` command.
+	GCFlags string `yaml:"gcflags"`
+	// LDFlags provides `-ldflags` option to `go build` command.
+	// It defaults to `-s -w -X main.version={{.Version}}`.
+	LDFlags string
+	// Mod provides `-mod` option to `go build` command: readonly,
+	// vendor, or mod.
+	Mod string
+	// Tags is a list of build tags, provided by `-tags` option to `go
+	// build` command.
+	Tags []string
+}
+
+// GoOverride represents build flags of targets matching its filters
+type GoOverride struct {
+	// Match is a list of OS-arch names, like linux-amd64, or artifact
+	// filters, matching the artifact of the target.
+	Match modules.Filters
+	// GoFlags are build flags of matching targets. Env entries are
+	// added to previous ones, Tags replace previous ones if set, and
+	// other flags replace previous ones if not empty.
+	GoFlags `yaml:",inline"`
+}
+
+// nolint: gochecknoglobals
+var (
+	buildModes = []string{"default", "exe", "pie", "c-shared", "c-archive", "archive", "shared", "plugin"}
+	modModes   = []string{"readonly", "vendor", "mod"}
+)
+
+// override returns flags overridden by another set of flags
+func (flags GoFlags) override(other *GoFlags) GoFlags {
+	replace := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+
+	replace(&flags.ASMFlags, other.ASMFlags)
+	replace(&flags.BuildMode, other.BuildMode)
+	replace(&flags.GCFlags, other.GCFlags)
+	replace(&flags.LDFlags, other.LDFlags)
+	replace(&flags.Mod, other.Mod)
+
+	if len(other.Env) > 0 {
+		flags.Env = append(append([]string{}, flags.Env...), other.Env...)
+	}
+
+	if other.Tags != nil {
+		flags.Tags = other.Tags
+	}
+
+	return flags
+}
+
+// validate checks build flags. Templated values are checked after
+// rendering. Problems are reported as FieldErrors of fields prefixed by
+// prefix.
+func (flags *GoFlags) validate(prefix string) error {
+	var errs modules.Errors
+
+	errs.Add(validateChoice(prefix+"buildmode", flags.BuildMode, buildModes))
+	errs.Add(validateChoice(prefix+"mod", flags.Mod, modModes))
+
+	for _, item := range flags.Env {
+		if !strings.Contains(item, "=") {
+			errs.Add(modules.NewFieldError(prefix+"env", "invalid entry %q, expecting KEY=value", item))
+		}
+	}
+
+	return errs.Err()
+}
+
+// validateChoice checks whether a non-templated value is one of the
+// valid values
+func validateChoice(field, value string, valid []string) error {
+	if value == "" || strings.Contains(value, "{{") {
+		return nil
+	}
+
+	for _, item := range valid {
+		if item == value {
+			return nil
+		}
+	}
+
+	return modules.NewFieldError(field, "invalid value %q, valid values are %s", value, strings.Join(valid, ", "))
+}
+
+// buildModeExt returns the file extension of a build mode's results on an
+// operating system
+func buildModeExt(mode, goos string) string {
+	switch mode {
+	case "c-shared", "shared", "plugin":
+		switch goos {
+		case "windows":
+			return ".dll"
+		case "darwin", "ios":
+			return ".dylib"
+		}
+
+		return ".so"
+	case "c-archive", "archive":
+		return ".a"
+	}
+
+	if goos == "windows" {
+		return ".exe"
+	}
+
+	return ""
 }
 
 // nolint: gochecknoinits
@@ -72,36 +236,226 @@ func init() {
 // NewGo is a Go struct factory
 func NewGo() modules.Pluggable {
 	return &Go{
-		LDFlags: "-s -w -X main.version={{.Version}}",
-		GOOS:    []string{"linux", "windows"},
-		GOArch:  []string{"amd64"},
-		GOArm:   []int32{6},
-		Main:    ".",
-		ID:      "default",
-		Output:  "{{.ProjectName}}{{OSExt}}",
+		GoFlags:     GoFlags{LDFlags: "-s -w -X main.version={{.Version}}"},
+		GOOS:        []string{"linux", "windows"},
+		GOArch:      []string{"amd64"},
+		GOArm:       []int32{6},
+		Main:        ".",
+		ID:          "default",
+		Output:      "{{.ProjectName}}{{.Ext}}",
+		Parallelism: runtime.GOMAXPROCS(0),
+	}
+}
+
+// Describe documents Go module
+func (*Go) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Runs `go build` for each GOOS-GOARCH combination",
+		Fields: map[string]string{
+			"after":             "Commands to run after builds",
+			"asmflags":          "-asmflags template for go build",
+			"before":            "Commands to run before builds",
+			"buildmode":         "-buildmode template for go build, like pie, c-shared, or c-archive",
+			"buildvcs":          "-buildvcs flag of go build: true, false, or auto",
+			"cgo":               "C toolchains of cgo builds: resolver name, like zig, and toolchains by OS-arch in targets",
+			"cgo_enabled":       "Sets CGO_ENABLED of builds, if set",
+			"env":               "Extra environment variable templates of builds, in KEY=value format",
+			"gcflags":           "-gcflags template for go build",
+			"goos":              "List of GOOS values",
+			"goarch":            "List of GOARCH values",
+			"goarm":             "List of GOARM values, effective for arm targets only",
+			"id":                "Resulting artifact ID",
+			"ldflags":           "-ldflags template for go build",
+			"main":              "Package where main function is defined",
+			"mod":               "-mod template for go build: readonly, vendor, or mod",
+			"mod_timestamp":     "Unix timestamp template of build results' modification time, like {{.Git.CommitTimestamp}}",
+			"output":            "Artifact file name template",
+			"overrides":         "Build flags of targets matching OS-arch names, or artifact filters in match",
+			"parallelism":       "Number of targets built concurrently. Default: GOMAXPROCS",
+			"source_date_epoch": "Sets SOURCE_DATE_EPOCH of builds to the commit time, unless it is set",
+			"skip":              "OS-arch combinations, or artifact filters to be skipped",
+			"tags":              "List of build tag templates",
+			"targets":           "List of os_arch, or os_arch_variant targets, instead of goos, goarch, and goarm",
+			"trimpath":          "Removes file system paths from build results",
+			"verify":            "Builds each target twice, and fails if the results differ",
+		},
+	}
+}
+
+// Validate checks build settings
+func (mod *Go) Validate() error {
+	var errs modules.Errors
+
+	if len(mod.Targets) == 0 {
+		errs.Add(modules.RequireList("goos", mod.GOOS))
+		errs.Add(modules.RequireList("goarch", mod.GOArch))
+	}
+
+	errs.Add(modules.RequireField("id", mod.ID))
+	errs.Add(modules.RequireField("main", mod.Main))
+	errs.Add(modules.RequireField("output", mod.Output))
+
+	for _, goarm := range mod.GOArm {
+		if goarm < 5 || goarm > 7 {
+			errs.Add(modules.NewFieldError("goarm", "invalid ARM version %d, valid values are 5, 6, and 7", goarm))
+		}
+	}
+
+	errs.Extend(mod.Skip.Validate("skip"))
+	errs.Extend(mod.GoFlags.validate(""))
+	errs.Extend(mod.Cgo.Validate())
+
+	for idx, override := range mod.Overrides {
+		prefix := fmt.Sprintf("overrides[%d].", idx)
+
+		if len(override.Match) == 0 {
+			errs.Add(modules.NewFieldError(prefix+"match", "must not be empty"))
+		}
+
+		errs.Extend(override.Match.Validate(prefix + "match"))
+		errs.Extend(override.GoFlags.validate(prefix))
+	}
+
+	errs.Extend(mod.validateTargets())
+
+	switch mod.BuildVCS {
+	case "", "true", "false", "auto":
+	default:
+		errs.Add(modules.NewFieldError("buildvcs", "invalid value %q, valid values are true, false, and auto", mod.BuildVCS))
 	}
+
+	if mod.Parallelism < 0 {
+		errs.Add(modules.NewFieldError("parallelism", "must not be negative"))
+	}
+
+	return errs.Err()
+}
+
+// Produces returns the artifact ID of builds
+func (mod *Go) Produces() []string {
+	return []string{mod.ID}
 }
 
 // Run executes a go build step
 func (mod *Go) Run(cx context.Context) error {
 	targets, err := mod.targets(cx)
-
 	if err != nil {
 		return err
 	}
 
+	if err := checkPlatforms(targets); err != nil {
+		return err
+	}
+
+	if err := checkCToolchains(targets); err != nil {
+		return err
+	}
+
 	if err := mod.runHooks(cx, mod.Before); err != nil {
 		return err
 	}
 
+	if err := mod.build(cx, targets); err != nil {
+		return err
+	}
+
+	if err := mod.runHooks(cx, mod.After); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// buildTarget builds a single target. It is replaced in tests.
+// nolint: gochecknoglobals
+var buildTarget = (*goSingleTarget).build
+
+// build runs all targets concurrently, limited by Parallelism. Artifacts
+// are registered in target order, regardless of which build finished
+// first. All build errors are collected and returned together.
+func (mod *Go) build(cx context.Context, targets []*goSingleTarget) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	workers := mod.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	artifacts := make([]*ctx.Artifact, len(targets))
+	errs := make([]error, len(targets))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				artifacts[idx], errs[idx] = buildTarget(targets[idx], cx)
+			}
+		}()
+	}
+
+	for idx := range targets {
+		jobs <- idx
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	var buildErrors modules.Errors
+
+	for idx, artifact := range artifacts {
+		if errs[idx] != nil {
+			buildErrors.Add(fmt.Errorf("%s: %w", targets[idx].OSArch(), errs[idx]))
+
+			continue
+		}
+
+		context.AddArtifact(artifact)
+	}
+
+	return buildErrors.Err()
+}
+
+// Plan describes go build steps, and predicts their artifacts
+func (mod *Go) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	targets, err := mod.targets(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range mod.Before {
+		plan.AddCommand(strings.Fields(hook)...)
+	}
+
 	for _, tar := range targets {
-		if err := tar.Run(cx); err != nil {
+		artifact := tar.artifact()
+
+		commands := [][]string{tar.command()}
+		if mod.Verify {
+			commands = tar.verifyCommands("<verify-1>", "<verify-2>")
+		}
+
+		for _, command := range commands {
+			plan.AddCommand(append(tar.goEnv(), command...)...)
+		}
+
+		plan.AddFile(artifact.Location)
+
+		if err := plan.AddArtifact(cx, artifact); err != nil {
 			return err
 		}
 	}
 
-	if err := mod.runHooks(cx, mod.After); err != nil {
-		return err
+	for _, hook := range mod.After {
+		plan.AddCommand(strings.Fields(hook)...)
 	}
 
 	return nil
@@ -119,7 +473,7 @@ func (mod *Go) runHooks(cx context.Context, hooks []string) error {
 
 	for _, hook := range hooks {
 		args := strings.Fields(hook)
-		if err := context.Env.Run(args[0], args[1:]...); err != nil {
+		if err := modules.RunCommand(cx, context.Env, args...); err != nil {
 			return err
 		}
 	}
@@ -127,31 +481,87 @@ func (mod *Go) runHooks(cx context.Context, hooks []string) error {
 	return nil
 }
 
-func (mod *Go) targets(cx context.Context) ([]modules.Pluggable, error) {
-	targets := []modules.Pluggable{}
+// validateTargets checks targets, and their microarchitecture variants.
+// Platforms are checked against the Go toolchain by Run only.
+func (mod *Go) validateTargets() error {
+	var errs modules.Errors
+
+	for _, target := range mod.Targets {
+		osarch, err := ctx.ParseTarget(target)
+		if err != nil {
+			errs.Add(modules.NewFieldError("targets", "%v", err))
+
+			continue
+		}
+
+		if err := validateVariant(osarch); err != nil {
+			errs.Add(modules.NewFieldError("targets", "%s: %v", target, err))
+		}
+	}
+
+	return errs.Err()
+}
+
+// platforms returns OS-arch combinations to build for, from Targets, or
+// from GOOS, GOArch, and GOArm
+func (mod *Go) platforms() ([]*ctx.OsArch, error) {
+	osarchs := []*ctx.OsArch{}
+
+	if len(mod.Targets) > 0 {
+		for _, target := range mod.Targets {
+			osarch, err := ctx.ParseTarget(target)
+			if err != nil {
+				return nil, err
+			}
+
+			osarchs = append(osarchs, osarch)
+		}
+
+		return osarchs, nil
+	}
 
 	for _, goos := range mod.GOOS {
 		for _, goarch := range mod.GOArch {
 			arms := []int32{0}
-			if goos == "linux" && goarch == "arm" {
+			if goarch == "arm" {
 				arms = mod.GOArm
 			}
 
 			for _, goarm := range arms {
-				target := mod.newSingleTarget(goos, goarch, goarm)
+				osarch := &ctx.OsArch{OS: goos, Arch: goarch}
+				if goarm > 0 {
+					osarch.Variant = strconv.Itoa(int(goarm))
+				}
 
-				err := target.Setup(cx)
-				if err != nil {
-					if errors.Is(err, ErrSkippedTarget) {
-						continue
-					}
+				osarchs = append(osarchs, osarch)
+			}
+		}
+	}
 
-					return nil, err
-				}
+	return osarchs, nil
+}
+
+func (mod *Go) targets(cx context.Context) ([]*goSingleTarget, error) {
+	osarchs, err := mod.platforms()
+	if err != nil {
+		return nil, err
+	}
 
-				targets = append(targets, target)
+	targets := []*goSingleTarget{}
+
+	for _, osarch := range osarchs {
+		target := mod.newSingleTarget(osarch)
+
+		err := target.Setup(cx)
+		if err != nil {
+			if errors.Is(err, ErrSkippedTarget) {
+				continue
 			}
+
+			return nil, err
 		}
+
+		targets = append(targets, target)
 	}
 
 	return targets, nil
@@ -0,0 +1,413 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const testPlatforms = `[
+	{"GOOS": "darwin", "GOARCH": "arm64", "CgoSupported": true, "FirstClass": true},
+	{"GOOS": "linux", "GOARCH": "amd64", "CgoSupported": true, "FirstClass": true},
+	{"GOOS": "linux", "GOARCH": "arm", "CgoSupported": true, "FirstClass": true},
+	{"GOOS": "windows", "GOARCH": "amd64", "CgoSupported": true, "FirstClass": true}
+]`
+
+func withTestPlatforms(t *testing.T) {
+	saved := listPlatforms
+
+	listPlatforms = func() ([]byte, error) { return []byte(testPlatforms), nil }
+	platformsOnce = sync.Once{}
+
+	t.Cleanup(func() {
+		listPlatforms = saved
+		platformsOnce = sync.Once{}
+	})
+}
+
+func TestGo_Validate_targets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		errStr  string
+	}{
+		{
+			name:    "targets",
+			targets: []string{"linux_amd64_v3", "linux_arm_7", "darwin_arm64", "windows_arm64"},
+		},
+		{
+			name:    "invalid targets",
+			targets: []string{"linux", "linux_amd64_v5", "linux_arm_8"},
+			errStr: strings.Join([]string{
+				`targets: invalid target "linux", expecting os_arch, or os_arch_variant`,
+				`targets: linux_amd64_v5: invalid GOAMD64 variant "v5", valid values are [v1 v2 v3 v4]`,
+				`targets: linux_arm_8: invalid ARM version 8, valid values are 5, 6, and 7`,
+			}, "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mod := NewGo().(*Go)
+			mod.Targets = tt.targets
+
+			err := mod.Validate()
+			if tt.errStr == "" {
+				if err != nil {
+					t.Errorf("Go.Validate() unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != tt.errStr {
+				t.Errorf("Go.Validate() error = %v, want %q", err, tt.errStr)
+			}
+		})
+	}
+}
+
+func Test_checkPlatforms(t *testing.T) {
+	withTestPlatforms(t)
+
+	mod := NewGo().(*Go)
+	targets := []*goSingleTarget{}
+
+	for _, osarch := range []*ctx.OsArch{
+		{OS: "linux", Arch: "amd64", Variant: "v3"},
+		{OS: "linux", Arch: "arm", Variant: "6"},
+		{OS: "windows", Arch: "arm"},
+		{OS: "linux", Arch: "arm", Variant: "7"},
+		{OS: "windows", Arch: "arm", Variant: "7"},
+	} {
+		targets = append(targets, mod.newSingleTarget(osarch))
+	}
+
+	err := checkPlatforms(targets)
+	if err == nil || err.Error() != "windows/arm is not supported by the Go toolchain" {
+		t.Errorf("checkPlatforms() error = %v", err)
+	}
+
+	if err := checkPlatforms(targets[:2]); err != nil {
+		t.Errorf("checkPlatforms() unexpected error: %v", err)
+	}
+
+	listPlatforms = func() ([]byte, error) { return nil, errors.New("go: not found") }
+	platformsOnce = sync.Once{}
+
+	err = checkPlatforms(targets[:2])
+	if err == nil || err.Error() != "listing platforms of the Go toolchain: go: not found" {
+		t.Errorf("checkPlatforms() error = %v", err)
+	}
+}
+
+func TestGo_platforms(t *testing.T) {
+	mod := NewGo().(*Go)
+	mod.GOOS = []string{"linux"}
+	mod.GOArch = []string{"amd64", "arm"}
+	mod.GOArm = []int32{6, 7}
+
+	osarchs, err := mod.platforms()
+	if err != nil {
+		t.Fatalf("Go.platforms() unexpected error: %v", err)
+	}
+
+	want := []*ctx.OsArch{
+		{OS: "linux", Arch: "amd64"},
+		{OS: "linux", Arch: "arm", Variant: "6"},
+		{OS: "linux", Arch: "arm", Variant: "7"},
+	}
+
+	if diff := deep.Equal(osarchs, want); diff != nil {
+		t.Errorf("Go.platforms() %v", diff)
+	}
+
+	target := mod.newSingleTarget(&ctx.OsArch{OS: "linux", Arch: "amd64", Variant: "v3"})
+
+	if diff := deep.Equal(target.goEnv(), []string{"GOOS=linux", "GOARCH=amd64", "GOAMD64=v3"}); diff != nil {
+		t.Errorf("goSingleTarget.goEnv() %v", diff)
+	}
+}
+
+func TestGo_reproducible(t *testing.T) {
+	cgo := false
+
+	mod := NewGo().(*Go)
+	mod.Trimpath = true
+	mod.BuildVCS = "false"
+	mod.CGOEnabled = &cgo
+	mod.Verify = true
+
+	target := mod.newSingleTarget(&ctx.OsArch{OS: "linux", Arch: "amd64"})
+	target.flags.LDFlags = "-s -w"
+	target.Output = "app"
+	target.sourceDate = "1700000000"
+
+	if diff := deep.Equal(
+		target.goEnv(),
+		[]string{"GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0", "SOURCE_DATE_EPOCH=1700000000"},
+	); diff != nil {
+		t.Errorf("goSingleTarget.goEnv() %v", diff)
+	}
+
+	want := [][]string{
+		{"go", "build", "-o", "a/app", "-trimpath", "-buildvcs=false", "-a", "-ldflags", "-s -w", "."},
+		{"go", "build", "-o", "b/app", "-trimpath", "-buildvcs=false", "-a", "-ldflags", "-s -w", "."},
+	}
+
+	if diff := deep.Equal(target.verifyCommands("a", "b"), want); diff != nil {
+		t.Errorf("goSingleTarget.verifyCommands() %v", diff)
+	}
+
+	mod.BuildVCS = "yes"
+	if err := mod.Validate(); err == nil || err.Error() != `buildvcs: invalid value "yes", valid values are true, false, and auto` {
+		t.Errorf("Go.Validate() error = %v", err)
+	}
+}
+
+func TestGo_flags(t *testing.T) {
+	withTestPlatforms(t)
+
+	config := `
+targets: [linux_amd64, windows_amd64, darwin_arm64]
+buildmode: c-shared
+mod: vendor
+tags: [netgo, "v{{.Version}}"]
+gcflags: all=-N -l
+ldflags: -s -w
+env: [CGO_ENABLED=0]
+overrides:
+- match: [linux-amd64]
+  env: [CGO_ENABLED=1, CC=gcc]
+  tags: [osusergo]
+`
+
+	mod := NewGo().(*Go)
+	if err := yaml.Unmarshal([]byte(config), mod); err != nil {
+		t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+	}
+
+	if err := mod.Validate(); err != nil {
+		t.Fatalf("Go.Validate() unexpected error: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "lib"
+	shipContext.Version = "1.0"
+
+	targets, err := mod.targets(cx)
+	if err != nil {
+		t.Fatalf("Go.targets() unexpected error: %v", err)
+	}
+
+	want := []struct {
+		output string
+		env    []string
+		cgo    string
+		tags   string
+	}{
+		{"lib.so", []string{"CGO_ENABLED=0", "CGO_ENABLED=1", "CC=gcc"}, "1", "osusergo"},
+		{"lib.dll", []string{"CGO_ENABLED=0"}, "0", "netgo,v1.0"},
+		{"lib.dylib", []string{"CGO_ENABLED=0"}, "0", "netgo,v1.0"},
+	}
+
+	if len(targets) != len(want) {
+		t.Fatalf("Go.targets() returned %d targets, want %d", len(targets), len(want))
+	}
+
+	for idx, target := range targets {
+		if target.Output != want[idx].output {
+			t.Errorf("%s: output = %q, want %q", target.OSArch(), target.Output, want[idx].output)
+		}
+
+		env := target.goEnv()
+		if diff := deep.Equal(env[len(env)-len(want[idx].env):], want[idx].env); diff != nil {
+			t.Errorf("%s: goSingleTarget.goEnv() %v", target.OSArch(), diff)
+		}
+
+		if value, _ := target.Env.Get("CGO_ENABLED"); value != want[idx].cgo {
+			t.Errorf("%s: CGO_ENABLED = %q, want %q", target.OSArch(), value, want[idx].cgo)
+		}
+
+		command := target.commandTo("out")
+		wantCommand := []string{
+			"go", "build", "-o", "out", "-buildmode=c-shared", "-mod=vendor",
+			"-tags", want[idx].tags, "-gcflags", "all=-N -l", "-ldflags", "-s -w", ".",
+		}
+
+		if diff := deep.Equal(command, wantCommand); diff != nil {
+			t.Errorf("%s: goSingleTarget.commandTo() %v", target.OSArch(), diff)
+		}
+	}
+
+	mod.Overrides = append(mod.Overrides, &GoOverride{GoFlags: GoFlags{BuildMode: "library", Env: []string{"CC"}}})
+
+	wantErr := strings.Join([]string{
+		"overrides[1].match: must not be empty",
+		"overrides[1].buildmode: invalid value \"library\", valid values are default, exe, pie, c-shared, c-archive, archive, shared, plugin",
+		"overrides[1].env: invalid entry \"CC\", expecting KEY=value",
+	}, "\n")
+
+	if err := mod.Validate(); err == nil || err.Error() != wantErr {
+		t.Errorf("Go.Validate() error = %v, want %q", err, wantErr)
+	}
+}
+
+func TestGo_cgo(t *testing.T) {
+	withTestPlatforms(t)
+
+	config := `
+targets: [linux_amd64, linux_arm_7, darwin_arm64]
+cgo:
+  resolver: zig
+  targets:
+    linux-*:
+      ldflags: -static
+    linux-amd64:
+      cflags: -O2 -DVERSION={{.Version}}
+`
+
+	mod := NewGo().(*Go)
+	if err := yaml.Unmarshal([]byte(config), mod); err != nil {
+		t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+	}
+
+	if err := mod.Validate(); err != nil {
+		t.Fatalf("Go.Validate() unexpected error: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.Version = "1.0"
+
+	targets, err := mod.targets(cx)
+	if err != nil {
+		t.Fatalf("Go.targets() unexpected error: %v", err)
+	}
+
+	want := [][]string{
+		{
+			"GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=1",
+			"CC=zig cc -target x86_64-linux-musl", "CXX=zig c++ -target x86_64-linux-musl", "AR=zig ar",
+			"CGO_CFLAGS=-O2 -DVERSION=1.0", "CGO_LDFLAGS=-static",
+		},
+		{
+			"GOOS=linux", "GOARCH=arm", "GOARM=7", "CGO_ENABLED=1",
+			"CC=zig cc -target arm-linux-musleabihf", "CXX=zig c++ -target arm-linux-musleabihf", "AR=zig ar",
+			"CGO_LDFLAGS=-static",
+		},
+		{
+			"GOOS=darwin", "GOARCH=arm64", "CGO_ENABLED=1",
+			"CC=zig cc -target aarch64-macos", "CXX=zig c++ -target aarch64-macos", "AR=zig ar",
+		},
+	}
+
+	for idx, target := range targets {
+		if diff := deep.Equal(target.goEnv(), want[idx]); diff != nil {
+			t.Errorf("%s: goSingleTarget.goEnv() %v", target.OSArch(), diff)
+		}
+	}
+
+	targets[0].cgo = &modules.CToolchain{CC: "go", AR: "goshipdone-missing-ar"}
+
+	err = checkCToolchains(targets[:1])
+	if err == nil || !strings.HasPrefix(err.Error(), `cgo: AR of linux-amd64: exec: "goshipdone-missing-ar"`) {
+		t.Errorf("checkCToolchains() error = %v", err)
+	}
+
+	mod.Cgo.Resolver = "clang"
+	if err := mod.Validate(); err == nil || err.Error() != `cgo.resolver: unknown resolver "clang", valid values are zig` {
+		t.Errorf("Go.Validate() error = %v", err)
+	}
+}
+
+func TestGo_build(t *testing.T) {
+	saved := buildTarget
+
+	t.Cleanup(func() { buildTarget = saved })
+
+	var running, maxRunning int32
+
+	buildTarget = func(tar *goSingleTarget, cx context.Context) (*ctx.Artifact, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+
+		// later targets finish first
+		time.Sleep(time.Duration(5-len(tar.Output)) * 10 * time.Millisecond)
+
+		if tar.osarch.OS == "windows" {
+			return nil, errors.New("build failed")
+		}
+
+		return &ctx.Artifact{ID: tar.ID, Filename: tar.Output, OsArch: tar.osarch}, nil
+	}
+
+	mod := NewGo().(*Go)
+	mod.Parallelism = 2
+	targets := []*goSingleTarget{}
+
+	for idx, osarch := range []*ctx.OsArch{
+		{OS: "darwin", Arch: "arm64"},
+		{OS: "linux", Arch: "amd64"},
+		{OS: "windows", Arch: "386"},
+		{OS: "linux", Arch: "arm64"},
+		{OS: "windows", Arch: "amd64"},
+	} {
+		target := mod.newSingleTarget(osarch)
+		target.Output = strings.Repeat("x", idx+1)
+		targets = append(targets, target)
+	}
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mod.build(cx, targets)
+	if err == nil || err.Error() != "windows-386: build failed\nwindows-amd64: build failed" {
+		t.Errorf("Go.build() error = %v", err)
+	}
+
+	if maxRunning != 2 {
+		t.Errorf("Go.build() ran %d builds concurrently, want 2", maxRunning)
+	}
+
+	got := []string{}
+	for _, art := range shipContext.Artifacts {
+		got = append(got, art.OsArch.String())
+	}
+
+	if diff := deep.Equal(got, []string{"darwin-arm64", "linux-amd64", "linux-arm64"}); diff != nil {
+		t.Errorf("Go.build() artifacts %v", diff)
+	}
+}
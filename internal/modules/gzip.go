@@ -0,0 +1,281 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Gzip is a module for compressing artifacts one by one, like bare
+// executables, into new artifacts
+type Gzip struct {
+	// Builds selects artifacts to be compressed, by their IDs, or by
+	// artifact filters.
+	Builds modules.Selector
+	// Compression is the compression format, or a mapping of the format,
+	// and the compression level. Default: gzip.
+	Compression Compression
+	// ID contains the artifact's name used by later stages of the build
+	// pipeline. Default: "compressed".
+	ID string
+	// Output is the file name template of compressed artifacts, where
+	// `{{.Filename}}` is the file name of the source artifact, and
+	// `{{.Ext}}` is the compression's extension.
+	// Default: `{{.ProjectName}}-{{OS}}-{{ArchName}}{{.Ext}}`.
+	Output string
+	// Remove removes source artifacts from the artifact list, so later
+	// modules see compressed artifacts only. Their files are kept.
+	Remove bool
+	// Skip specifies which os-arch items, or artifact filters should be
+	// skipped
+	Skip modules.Skip
+}
+
+// gzipTarget is a compressed artifact of a source artifact
+type gzipTarget struct {
+	artifact *ctx.Artifact
+	source   *ctx.Artifact
+}
+
+// nolint: gochecknoinits
+func init() {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "gzip",
+		Factory: NewGzip,
+	})
+}
+
+// NewGzip is a Gzip struct factory
+func NewGzip() modules.Pluggable {
+	return &Gzip{
+		Builds:      modules.SelectIDs("default"),
+		Compression: Compression{Compressor: &CompressGz{}},
+		ID:          "compressed",
+		Output:      "{{.ProjectName}}-{{OS}}-{{ArchName}}{{.Ext}}",
+		Skip:        modules.Skip{},
+	}
+}
+
+// Describe documents Gzip module
+func (*Gzip) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Compresses artifacts one by one into new artifacts",
+		Fields: map[string]string{
+			"builds":      "Artifact IDs, or artifact filters to be compressed",
+			"compression": "Compression format, or a mapping of format, and level. xz levels select the dictionary size only",
+			"id":          "Resulting artifact ID",
+			"output":      "Compressed file name template",
+			"remove":      "Removes source artifacts from the artifact list",
+			"skip":        "OS-arch combinations, or artifact filters to be skipped",
+		},
+	}
+}
+
+// Validate checks gzip settings
+func (mod *Gzip) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", mod.Builds))
+	errs.Extend(mod.Builds.Validate("builds"))
+	errs.Extend(mod.Skip.Validate("skip"))
+	errs.Add(modules.RequireField("id", mod.ID))
+	errs.Add(modules.RequireField("output", mod.Output))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs to be compressed
+func (mod *Gzip) Consumes() []string {
+	return mod.Builds.IDs()
+}
+
+// Produces returns the artifact ID of compressed artifacts, and IDs of
+// source artifacts, if they are removed
+func (mod *Gzip) Produces() []string {
+	if mod.Remove {
+		return append([]string{mod.ID}, mod.Builds.IDs()...)
+	}
+
+	return []string{mod.ID}
+}
+
+// Run compresses selected artifacts, and registers the results
+func (mod *Gzip) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	targets, err := mod.targets(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		if err := cx.Err(); err != nil {
+			return err
+		}
+
+		if err := mod.compress(target); err != nil {
+			return err
+		}
+
+		if mod.Remove {
+			context.RemoveArtifact(target.source)
+		}
+
+		context.AddArtifact(target.artifact)
+	}
+
+	return nil
+}
+
+// Plan describes files to be written, and predicts their artifacts
+func (mod *Gzip) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	targets, err := mod.targets(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		plan.AddFile(target.artifact.Location)
+
+		if mod.Remove {
+			context.RemoveArtifact(target.source)
+		}
+
+		if err := plan.AddArtifact(cx, target.artifact); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// targets returns compressed artifacts of selected artifacts, in OS-arch
+// order
+func (mod *Gzip) targets(cx context.Context) ([]*gzipTarget, error) {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := modules.NewTemplate(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	td.Ext = mod.Compression.Extension()
+
+	artifactMap := context.ListArtifacts().OsArchBySelector(mod.Builds.Skipping(mod.Skip))
+	targets := []*gzipTarget{}
+	outputs := map[string]string{}
+
+	for _, osarch := range sortedOsArchs(artifactMap) {
+		for _, source := range *artifactMap[osarch] {
+			td.OSArch = source.OsArch
+			td.Filename = path.Base(source.Filename)
+
+			output, err := td.Parse("gzip", mod.Output)
+			if err != nil {
+				return nil, fmt.Errorf("rendering %q: %w", mod.Output, err)
+			}
+
+			output = path.Clean(output)
+
+			if other, ok := outputs[output]; ok {
+				return nil, fmt.Errorf("%s and %s are both compressed into %s", other, source.Filename, output)
+			}
+
+			outputs[output] = source.Filename
+
+			targets = append(targets, &gzipTarget{
+				artifact: mod.artifact(source, output, context.TargetDir),
+				source:   source,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// artifact returns the compressed artifact of a source artifact, with
+// its kind, OS-arch, and extra attributes
+func (mod *Gzip) artifact(source *ctx.Artifact, output, targetDir string) *ctx.Artifact {
+	extra := map[string]interface{}{}
+
+	for key, value := range source.Extra {
+		extra[key] = value
+	}
+
+	extra["compressor"] = strings.ToLower(mod.Compression.String())
+
+	return &ctx.Artifact{
+		Filename: output,
+		Location: path.Join(targetDir, output),
+		ID:       mod.ID,
+		Kind:     source.Kind,
+		OsArch:   source.OsArch,
+		Extra:    extra,
+	}
+}
+
+// compress writes the compressed file of a target. Partial files are
+// removed on errors.
+func (mod *Gzip) compress(target *gzipTarget) error {
+	location := target.artifact.Location
+
+	err := mod.write(target.source.Location, location)
+	if err != nil {
+		_ = os.Remove(location)
+
+		return fmt.Errorf("compressing %s into %s: %w", target.source.Filename, location, err)
+	}
+
+	return target.artifact.Stat()
+}
+
+func (mod *Gzip) write(source, destination string) error {
+	reader, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	if err := os.MkdirAll(path.Dir(destination), 0o755); err != nil {
+		return err
+	}
+
+	writer, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	compressor, err := mod.Compression.NewWriter(writer)
+	if err == nil {
+		_, err = io.Copy(compressor, reader)
+
+		if closeErr := compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package modules
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGzip_Run(t *testing.T) {
+	dir := t.TempDir()
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "tool"
+	shipContext.TargetDir = dir
+
+	for _, goos := range []string{"linux", "windows"} {
+		location := path.Join(dir, goos, "tool")
+		if err := os.MkdirAll(path.Dir(location), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(location, []byte(goos+" binary"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		shipContext.Artifacts.Add(&ctx.Artifact{
+			ID:       "default",
+			Filename: "tool",
+			Location: location,
+			Kind:     ctx.KindBinary,
+			OsArch:   &ctx.OsArch{OS: goos, Arch: "amd64"},
+			Extra:    map[string]interface{}{"buildmode": "exe"},
+		})
+	}
+
+	shipContext.Artifacts.Add(&ctx.Artifact{ID: "changes", Filename: "CHANGES.md", Kind: ctx.KindReleaseNotes})
+
+	mod := NewGzip().(*Gzip)
+	if err := yaml.Unmarshal([]byte("remove: true\nskip: windows-*"), mod); err != nil {
+		t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Gzip.Run() unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, art := range shipContext.Artifacts {
+		got = append(got, art.ID+" "+art.Filename+" "+art.OsArch.String())
+	}
+
+	want := []string{"default tool windows-amd64", "changes CHANGES.md noarch", "compressed tool-linux-amd64.gz linux-amd64"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Gzip.Run() artifacts %v", diff)
+	}
+
+	compressed := shipContext.Artifacts[2]
+	if compressed.Kind != ctx.KindBinary || compressed.Extra["compressor"] != "gzip" || compressed.Extra["buildmode"] != "exe" {
+		t.Errorf("Gzip.Run() artifact = %+v", compressed)
+	}
+
+	file, err := os.Open(compressed.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := io.ReadAll(gz); err != nil || string(content) != "linux binary" {
+		t.Errorf("compressed content = %q, %v", content, err)
+	}
+
+	mod = NewGzip().(*Gzip)
+	mod.Builds = modules.SelectIDs("default", "compressed")
+	mod.Output = "{{.ProjectName}}{{.Ext}}"
+
+	if err := mod.Run(cx); err == nil || err.Error() != "tool-linux-amd64.gz and tool are both compressed into tool.gz" {
+		t.Errorf("Gzip.Run() error = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sync"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// goPlatform is a platform supported by the installed Go toolchain, as
+// listed by `go tool dist list -json`
+type goPlatform struct {
+	GOOS         string
+	GOARCH       string
+	CgoSupported bool
+	FirstClass   bool
+}
+
+// nolint: gochecknoglobals
+var (
+	platformsOnce sync.Once
+	platforms     map[string]*goPlatform
+	platformsErr  error
+
+	// listPlatforms returns platforms of the Go toolchain in JSON
+	// format. It is replaced in tests.
+	listPlatforms = func() ([]byte, error) {
+		return exec.Command("go", "tool", "dist", "list", "-json").Output()
+	}
+
+	// variantValues lists valid microarchitecture variants by
+	// architecture. Architectures not listed accept any variants.
+	variantValues = map[string][]string{
+		"386":      {"sse2", "softfloat"},
+		"amd64":    {"v1", "v2", "v3", "v4"},
+		"mips":     {"hardfloat", "softfloat"},
+		"mipsle":   {"hardfloat", "softfloat"},
+		"mips64":   {"hardfloat", "softfloat"},
+		"mips64le": {"hardfloat", "softfloat"},
+		"ppc64":    {"power8", "power9", "power10"},
+		"ppc64le":  {"power8", "power9", "power10"},
+		"riscv64":  {"rva20u64", "rva22u64", "rva23u64"},
+	}
+
+	arm64Variant = regexp.MustCompile(`^v(8\.[0-9]|9\.[0-5])(,(lse|crypto))*$`)
+)
+
+// goPlatforms returns platforms supported by the installed Go toolchain,
+// keyed by GOOS/GOARCH. The toolchain is asked only once.
+func goPlatforms() (map[string]*goPlatform, error) {
+	platformsOnce.Do(func() {
+		out, err := listPlatforms()
+		if err != nil {
+			platformsErr = fmt.Errorf("listing platforms of the Go toolchain: %w", err)
+
+			return
+		}
+
+		var list []*goPlatform
+		if err := json.Unmarshal(out, &list); err != nil {
+			platformsErr = fmt.Errorf("listing platforms of the Go toolchain: %w", err)
+
+			return
+		}
+
+		platforms = make(map[string]*goPlatform, len(list))
+
+		for _, platform := range list {
+			platforms[platform.GOOS+"/"+platform.GOARCH] = platform
+		}
+	})
+
+	return platforms, platformsErr
+}
+
+// checkPlatforms checks whether the installed Go toolchain supports the
+// platforms of targets. It fails if the toolchain cannot be asked.
+func checkPlatforms(targets []*goSingleTarget) error {
+	list, err := goPlatforms()
+	if err != nil {
+		return err
+	}
+
+	var errs modules.Errors
+
+	checked := map[string]bool{}
+
+	for _, target := range targets {
+		platform := target.osarch.OS + "/" + target.osarch.Arch
+		if checked[platform] {
+			continue
+		}
+
+		checked[platform] = true
+
+		if list[platform] == nil {
+			errs.Add(fmt.Errorf("%s is not supported by the Go toolchain", platform))
+		}
+	}
+
+	return errs.Err()
+}
+
+// validateVariant checks the microarchitecture variant of an OS-arch
+func validateVariant(osarch *ctx.OsArch) error {
+	if osarch.Variant == "" {
+		return nil
+	}
+
+	if osarch.Arch == "arm" {
+		switch osarch.Variant {
+		case "5", "6", "7":
+			return nil
+		}
+
+		return fmt.Errorf("invalid ARM version %s, valid values are 5, 6, and 7", osarch.Variant)
+	}
+
+	if osarch.Arch == "arm64" {
+		if !arm64Variant.MatchString(osarch.Variant) {
+			return fmt.Errorf("invalid GOARM64 variant %q, expecting v8.0 to v9.5, with optional lse, and crypto options", osarch.Variant)
+		}
+
+		return nil
+	}
+
+	valid, ok := variantValues[osarch.Arch]
+	if !ok {
+		return nil
+	}
+
+	for _, value := range valid {
+		if value == osarch.Variant {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid %s variant %q, valid values are %v", ctx.VariantVar(osarch.Arch), osarch.Variant, valid)
+}
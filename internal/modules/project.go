@@ -30,6 +30,32 @@ func NewProject() modules.Pluggable {
 	}
 }
 
+// Describe documents Project module
+func (*Project) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Sets basic project-specific data",
+		Fields: map[string]string{
+			"name":   "Project name. Default: current directory name",
+			"target": "Directory to put build results into",
+		},
+	}
+}
+
+// Validate checks project settings
+func (mod *Project) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireField("name", mod.Name))
+	errs.Add(modules.RequireField("target", mod.TargetDir))
+
+	return errs.Err()
+}
+
+// Plan records project's basic information, as it has no side effects
+func (mod *Project) Plan(cx context.Context, _ *modules.ModulePlan) error {
+	return mod.Run(cx)
+}
+
 // Run records project's basic information into ctx.Context
 func (mod *Project) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
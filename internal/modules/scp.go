@@ -5,17 +5,22 @@ import (
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // SCP is a module for uploading artifacts to a remote server via scp
 type SCP struct {
-	// Builds specifies which build names should be added to the archive.
-	Builds []string
+	// Builds selects artifacts to be uploaded, by their IDs, or by
+	// artifact filters.
+	Builds modules.Selector
+	// Retry specifies the retry policy of the upload. Default: 3
+	// attempts, starting with 2s backoff, retrying network, server, and
+	// rate limit errors. Failing scp commands are retried only if the
+	// command class is selected.
+	Retry *modules.Retry
 	// Skip specifies GOOS-GOArch combinations to be skipped.
-	// They are in `{{.Os}}-{{.Arch}}` format.
+	// They are in `{{.Os}}-{{.Arch}}` format, or artifact filters.
 	// It filters builds to be included.
-	Skip []string
+	Skip modules.Skip
 	// Target specifies SCP endpoint as the last parameter of the `scp`
 	// command. Example: staticfiles@remoteserver.com:/var/www/default/public
 	Target string
@@ -24,12 +29,44 @@ type SCP struct {
 // NewSCP is a factory function for SCP module
 func NewSCP() modules.Pluggable {
 	return &SCP{
-		Builds: []string{"archive"},
-		Skip:   []string{},
+		Builds: modules.SelectIDs("archive"),
+		Retry:  modules.DefaultRetry(),
+		Skip:   modules.Skip{},
 		Target: "",
 	}
 }
 
+// Describe documents SCP module
+func (*SCP) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Uploads artifacts to an SSH server with scp",
+		Fields: map[string]string{
+			"builds": "Artifact IDs, or artifact filters to be uploaded",
+			"retry":  "Retry policy of the upload: attempts, backoff, max_backoff, and error classes (on)",
+			"skip":   "OS-arch combinations, or artifact filters to be skipped",
+			"target": "SCP endpoint, like user@host:/path",
+		},
+	}
+}
+
+// Validate checks scp settings
+func (mod *SCP) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", mod.Builds))
+	errs.Extend(mod.Builds.Validate("builds"))
+	errs.Extend(mod.Skip.Validate("skip"))
+	errs.Add(modules.RequireField("target", mod.Target))
+	errs.Extend(mod.Retry.Validate("retry"))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs to be uploaded
+func (mod *SCP) Consumes() []string {
+	return mod.Builds.IDs()
+}
+
 // Run takes specified artifacts, and uploads them to a SSH server
 func (mod *SCP) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
@@ -37,17 +74,36 @@ func (mod *SCP) Run(cx context.Context) error {
 		return err
 	}
 
-	builds := context.Artifacts.OsArchByIDs(mod.Builds, mod.Skip)
+	args := append([]string{"scp"}, mod.args(context)...)
+
+	return mod.Retry.Do(cx, "scp", func() error {
+		return modules.RunCommand(cx, nil, args...)
+	})
+}
+
+// Plan describes the scp command to be run
+func (mod *SCP) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	plan.AddCommand(append([]string{"scp"}, mod.args(context)...)...)
+	plan.AddRemoteCall("upload files to %s", mod.Target)
+
+	return nil
+}
+
+func (mod *SCP) args(context *ctx.Context) []string {
+	builds := context.ListArtifacts().OsArchBySelector(mod.Builds.Skipping(mod.Skip))
 
 	cmdArgs := []string{}
 
-	for osarch := range builds {
+	for _, osarch := range sortedOsArchs(builds) {
 		for _, artifact := range *builds[osarch] {
 			cmdArgs = append(cmdArgs, artifact.Location)
 		}
 	}
 
-	cmdArgs = append(cmdArgs, mod.Target)
-
-	return sh.RunV("scp", cmdArgs...)
+	return append(cmdArgs, mod.Target)
 }
@@ -2,8 +2,10 @@ package modules
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sort"
+	"strings"
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
@@ -17,6 +19,18 @@ func NewShow() modules.Pluggable {
 	return &Show{}
 }
 
+// Describe documents Show module
+func (Show) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Lists environment variables and artifacts recorded so far",
+	}
+}
+
+// Plan does nothing, as Show doesn't change anything
+func (Show) Plan(context.Context, *modules.ModulePlan) error {
+	return nil
+}
+
 // Run provides a list of artifacts recorded so far
 func (Show) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
@@ -39,9 +53,40 @@ func (Show) Run(cx context.Context) error {
 
 	log.Printf("Artifacts:")
 
-	for _, art := range context.Artifacts {
-		log.Printf("- %s: %s (%s)", art.ID, art.Filename, art.OsArch.String())
+	for _, art := range *context.ListArtifacts() {
+		log.Printf("- %s: %s (%s)%s", art.ID, art.Filename, art.OsArch.String(), describeArtifact(art))
 	}
 
 	return nil
 }
+
+// describeArtifact returns kind, size, and extra attributes of an
+// artifact, if known
+func describeArtifact(art *ctx.Artifact) string {
+	details := []string{}
+
+	if art.Kind != ctx.KindUnknown {
+		details = append(details, art.Kind.String())
+	}
+
+	if art.Size > 0 {
+		details = append(details, fmt.Sprintf("%d bytes", art.Size))
+	}
+
+	keys := make([]string, 0, len(art.Extra))
+	for key := range art.Extra {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		details = append(details, fmt.Sprintf("%s=%v", key, art.Extra[key]))
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+
+	return ": " + strings.Join(details, ", ")
+}
@@ -28,6 +28,26 @@ func NewSkipPublish() modules.Pluggable {
 	}
 }
 
+// Describe documents SkipPublish module
+func (*SkipPublish) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Enables publish stage if an environment variable is set to a falsey value",
+		Fields: map[string]string{
+			"env_name": "Environment variable name instructing publish stage to be skipped",
+		},
+	}
+}
+
+// Validate checks publish settings
+func (mod *SkipPublish) Validate() error {
+	return modules.RequireField("env_name", mod.EnvName)
+}
+
+// Plan reads publish settings, as it has no side effects
+func (mod *SkipPublish) Plan(cx context.Context, _ *modules.ModulePlan) error {
+	return mod.Run(cx)
+}
+
 func (mod *SkipPublish) Run(cx context.Context) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
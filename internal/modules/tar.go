@@ -13,8 +13,9 @@ import (
 type (
 	// Tar is a module for building an archive from prior builds
 	Tar struct {
-		// Builds specifies which build names should be added to the archive.
-		Builds []string
+		// Builds selects artifacts to be added to the archive, by their
+		// IDs, or by artifact filters.
+		Builds modules.Selector
 		// CommonDir contains a common directory name for all files inside
 		// the tar archive. An empty CommonDir skips creating subdirectories.
 		// Default: `{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}`.
@@ -22,9 +23,10 @@ type (
 		// Compression specifies which compression should be applied to the
 		// archive.
 		Compression Compression
-		// Files contains a list of static files should be added to the
-		// archive file. They are interpretered as glob.
-		Files []string
+		// Files contains static files to be added to the archive. Entries
+		// are globs, or mappings of src, dst, mode, strip_parent, and skip.
+		// Default: `README*`.
+		Files []*ArchiveFile
 		// ID contains the artifact's name used by later stages of the build
 		// pipeline. Archives, and Publishes may refer to this name for
 		// referencing build results.
@@ -35,38 +37,111 @@ type (
 		// where `{{.Ext}}` contains the compression's default extension
 		Output string
 		// Skip specifies GOOS-GOArch combinations to be skipped.
-		// They are in `{{.Os}}-{{.Arch}}` format.
+		// They are in `{{.Os}}-{{.Arch}}` format, or artifact filters.
 		// It filters builds to be included.
-		Skip []string
+		Skip modules.Skip
+		// ArchiveSettings are settings of archive entries.
+		ArchiveSettings `yaml:",inline"`
 	}
 )
 
 func NewTar() modules.Pluggable {
 	return &Tar{
-		Builds:      []string{"default"},
+		Builds:      modules.SelectIDs("default"),
 		CommonDir:   "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}",
-		Compression: Compression{&CompressNONE{}},
-		Files:       []string{"README*"},
+		Compression: Compression{Compressor: &CompressNONE{}},
+		Files:       []*ArchiveFile{{Src: "README*"}},
 		ID:          "archive",
 		Output:      "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}.tar{{.Ext}}",
-		Skip:        []string{},
+		Skip:        modules.Skip{},
 	}
 }
 
+// Describe documents Tar module
+func (*Tar) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Puts artifacts into a tar archive for each OS-arch combination",
+		Fields: map[string]string{
+			"builds":      "Artifact IDs, or artifact filters to be put into archives",
+			"commondir":   "Topmost directory name template inside archives",
+			"dir_mode":    "Mode of directory entries, like 0755",
+			"file_mode":   "Mode of file entries, like 0644, with execute permissions for executables",
+			"compression": "Compression format: none, gzip, xz, or zstd, or a mapping of format, and level. xz levels select the dictionary size only",
+			"files":       "Static files to be added to archives: globs, or mappings of src, dst, mode, strip_parent, and skip",
+			"id":          "Resulting artifact ID",
+			"normalize":   "Sorts entries, owned by root, with SOURCE_DATE_EPOCH, or commit time as modification time",
+			"output":      "Archive file name template",
+			"skip":        "OS-arch combinations, or artifact filters to be skipped",
+			"tar_format":  "Format of tar archives: pax, gnu, or ustar",
+		},
+	}
+}
+
+// Validate checks archive settings
+func (mod *Tar) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", mod.Builds))
+	errs.Extend(mod.Builds.Validate("builds"))
+	errs.Extend(mod.Skip.Validate("skip"))
+	errs.Add(modules.RequireField("id", mod.ID))
+	errs.Add(modules.RequireField("output", mod.Output))
+	errs.Add(mod.ArchiveSettings.validate())
+	errs.Extend(validateFiles(mod.Files))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs put into archives
+func (mod *Tar) Consumes() []string {
+	return mod.Builds.IDs()
+}
+
+// Produces returns the artifact ID of archives
+func (mod *Tar) Produces() []string {
+	return []string{mod.ID}
+}
+
 func (mod *Tar) Run(cx context.Context) error {
+	return runArchives(cx, mod.Builds.Skipping(mod.Skip), mod.singleTarget)
+}
+
+// Plan describes archives to be created, and predicts their artifacts
+func (mod *Tar) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	return planArchives(cx, plan, mod.Builds.Skipping(mod.Skip), mod.singleTarget)
+}
+
+func (mod *Tar) singleTarget(cx context.Context, artifacts *ctx.Artifacts) (*archiveSingleTarget, error) {
+	return newArchiveTarget(cx, &archiveSingleTarget{
+		CommonDir:   mod.CommonDir,
+		Compression: mod.Compression,
+		Files:       mod.Files,
+		ID:          mod.ID,
+		Output:      mod.Output,
+		Settings:    mod.ArchiveSettings,
+	}, mod.Compression.Extension(), artifacts)
+}
+
+// archiveTargetFactory returns the archive target of artifacts of the
+// same OS-arch
+type archiveTargetFactory func(context.Context, *ctx.Artifacts) (*archiveSingleTarget, error)
+
+// runArchives writes an archive of selected artifacts for each OS-arch
+// combination, in alphabetical order
+func runArchives(cx context.Context, sel *ctx.Selector, singleTarget archiveTargetFactory) error {
 	context, err := ctx.GetShipContext(cx)
 	if err != nil {
 		return err
 	}
 
-	builds := context.Artifacts.OsArchByIDs(mod.Builds, mod.Skip)
+	builds := context.ListArtifacts().OsArchBySelector(sel)
 
 	if err := validateBuilds(builds); err != nil {
 		return err
 	}
 
-	for osarch := range builds {
-		target, err := mod.singleTarget(cx, builds[osarch])
+	for _, osarch := range sortedOsArchs(builds) {
+		target, err := singleTarget(cx, builds[osarch])
 		if err != nil {
 			return err
 		}
@@ -79,6 +154,55 @@ func (mod *Tar) Run(cx context.Context) error {
 	return nil
 }
 
+// planArchives describes archives of selected artifacts, and predicts
+// their artifacts
+func planArchives(
+	cx context.Context,
+	plan *modules.ModulePlan,
+	sel *ctx.Selector,
+	singleTarget archiveTargetFactory,
+) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	builds := context.ListArtifacts().OsArchBySelector(sel)
+
+	if err := validateBuilds(builds); err != nil {
+		return err
+	}
+
+	for _, osarch := range sortedOsArchs(builds) {
+		target, err := singleTarget(cx, builds[osarch])
+		if err != nil {
+			return err
+		}
+
+		artifact := target.artifact(context.TargetDir)
+		plan.AddFile(artifact.Location)
+
+		if err := plan.AddArtifact(cx, artifact); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedOsArchs returns os-arch keys of a build map in alphabetical order
+func sortedOsArchs(builds map[string]*ctx.Artifacts) []string {
+	keys := make([]string, 0, len(builds))
+
+	for osarch := range builds {
+		keys = append(keys, osarch)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
 func validateBuilds(builds map[string]*ctx.Artifacts) error {
 	numTargets := 0
 	lastosarch := ""
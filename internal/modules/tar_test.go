@@ -1,9 +1,22 @@
 package modules
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-test/deep"
 	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
 )
 
 // nolint: funlen
@@ -103,3 +116,311 @@ func Test_errNumTargets(t *testing.T) {
 		})
 	}
 }
+
+func Test_archiveSingleTarget_Run_interrupted(t *testing.T) {
+	dir := t.TempDir()
+	source := path.Join(dir, "binary")
+
+	if err := os.WriteFile(source, []byte("binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cx := ctx.New(cancelled)
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.TargetDir = dir
+
+	target := &archiveSingleTarget{
+		Compression: Compression{Compressor: &CompressGz{}},
+		ID:          "archive",
+		Output:      "archive.tar.gz",
+		Targets:     &ctx.Artifacts{{Filename: "binary", Location: source}},
+	}
+
+	if err := target.Run(cx); !errors.Is(err, context.Canceled) {
+		t.Errorf("archiveSingleTarget.Run() error = %v, want context canceled", err)
+	}
+
+	if _, err := os.Stat(path.Join(dir, "archive.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("archiveSingleTarget.Run() left partial archive: %v", err)
+	}
+
+	if len(shipContext.Artifacts) != 0 {
+		t.Errorf("archiveSingleTarget.Run() registered %d artifacts", len(shipContext.Artifacts))
+	}
+}
+
+func TestTar_normalize(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]os.FileMode{"tool": 0o700, "NOTES": 0o600}
+
+	for name, mode := range sources {
+		if err := os.WriteFile(path.Join(dir, name), []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mod := NewTar().(*Tar)
+	if err := yaml.Unmarshal([]byte("normalize: true\ntar_format: pax\ndir_mode: 0750\ncompression: gz"), mod); err != nil {
+		t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+	}
+
+	build := func(modTime time.Time) []byte {
+		for name := range sources {
+			if err := os.Chtimes(path.Join(dir, name), modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cx := ctx.New(context.Background())
+
+		shipContext, err := ctx.GetShipContext(cx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shipContext.ProjectName = "app"
+		shipContext.Version = "1.0"
+		shipContext.TargetDir = dir
+		shipContext.Git.CommitTimestamp = 1700000000
+
+		for _, name := range []string{"tool", "NOTES"} {
+			shipContext.Artifacts.Add(&ctx.Artifact{
+				ID:       "default",
+				Filename: name,
+				Location: path.Join(dir, name),
+				OsArch:   &ctx.OsArch{OS: "linux", Arch: "amd64"},
+			})
+		}
+
+		if err := mod.Run(cx); err != nil {
+			t.Fatalf("Tar.Run() unexpected error: %v", err)
+		}
+
+		content, err := os.ReadFile(path.Join(dir, "app-1.0-linux-amd64.tar.gz"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return content
+	}
+
+	first := build(time.Unix(1600000000, 0))
+	if second := build(time.Unix(1650000000, 0)); !bytes.Equal(first, second) {
+		t.Errorf("Tar.Run() archives differ with different source modification times")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gz.Header.ModTime.Unix() > 0 || gz.Header.Name != "" {
+		t.Errorf("gzip header = %+v", gz.Header)
+	}
+
+	got := []string{}
+	reader := tar.NewReader(gz)
+
+	for {
+		hdr, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hdr.Uid != 0 || hdr.Gid != 0 || hdr.Uname != "root" || hdr.Gname != "root" {
+			t.Errorf("%s: owner = %d/%s, group = %d/%s", hdr.Name, hdr.Uid, hdr.Uname, hdr.Gid, hdr.Gname)
+		}
+
+		if hdr.ModTime.Unix() != 1700000000 {
+			t.Errorf("%s: modification time = %v", hdr.Name, hdr.ModTime)
+		}
+
+		got = append(got, fmt.Sprintf("%s %04o", hdr.Name, hdr.Mode))
+	}
+
+	want := []string{
+		"app-1.0-linux-amd64/ 0750",
+		"app-1.0-linux-amd64/NOTES 0644",
+		"app-1.0-linux-amd64/tool 0755",
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("tar entries %v", diff)
+	}
+
+	mod.TarFormat = "v7"
+	if err := mod.Validate(); err == nil || err.Error() != `tar_format: invalid value "v7", valid values are pax, gnu, and ustar` {
+		t.Errorf("Tar.Validate() error = %v", err)
+	}
+}
+
+func TestTar_files(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"docs/man/app.1":        "man",
+		"LICENSE.md":            "license",
+		"completions/app.bash":  "bash",
+		"completions/zsh/_app":  "zsh",
+		"app-linux-amd64/app":   "linux",
+		"app-windows-amd64/app": "windows",
+	} {
+		if err := os.MkdirAll(path.Join(dir, path.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Symlink("app.bash", path.Join(dir, "completions", "app")); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = os.Chdir(wd) }()
+
+	mod := NewTar().(*Tar)
+	if err := yaml.Unmarshal([]byte(`normalize: true
+commondir: ""
+files:
+  - README*
+  - src: docs/man/*.1
+    dst: share/man/man1/
+    strip_parent: true
+  - src: LICENSE.md
+    dst: LICENSE
+    mode: 0600
+  - src: completions
+    dst: share/{{OS}}/completions
+    skip: windows-*
+`), mod); err != nil {
+		t.Fatalf("yaml.Unmarshal() unexpected error: %v", err)
+	}
+
+	if err := mod.Validate(); err != nil {
+		t.Fatalf("Tar.Validate() unexpected error: %v", err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "app"
+	shipContext.Version = "1.0"
+	shipContext.TargetDir = dir
+	shipContext.Git.CommitTimestamp = 1700000000
+
+	for _, goos := range []string{"linux", "windows"} {
+		shipContext.Artifacts.Add(&ctx.Artifact{
+			ID:       "default",
+			Filename: "app",
+			Location: path.Join(dir, "app-"+goos+"-amd64", "app"),
+			OsArch:   &ctx.OsArch{OS: goos, Arch: "amd64"},
+		})
+	}
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Tar.Run() unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		output string
+		want   []string
+	}{
+		{
+			output: "app-1.0-linux-amd64.tar",
+			want: []string{
+				"LICENSE 0600",
+				"app 0644",
+				"share/ 0755",
+				"share/linux/ 0755",
+				"share/linux/completions/ 0755",
+				"share/linux/completions/app 0777 -> app.bash",
+				"share/linux/completions/app.bash 0644",
+				"share/linux/completions/zsh/ 0755",
+				"share/linux/completions/zsh/_app 0644",
+				"share/man/ 0755",
+				"share/man/man1/ 0755",
+				"share/man/man1/app.1 0644",
+			},
+		},
+		{
+			output: "app-1.0-windows-amd64.tar",
+			want: []string{
+				"LICENSE 0600",
+				"app 0644",
+				"share/ 0755",
+				"share/man/ 0755",
+				"share/man/man1/ 0755",
+				"share/man/man1/app.1 0644",
+			},
+		},
+	} {
+		archive, err := os.Open(path.Join(dir, tt.output))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		reader := tar.NewReader(archive)
+
+		for {
+			hdr, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entry := fmt.Sprintf("%s %04o", hdr.Name, hdr.Mode)
+			if hdr.Typeflag == tar.TypeSymlink {
+				entry += " -> " + hdr.Linkname
+			}
+
+			got = append(got, entry)
+		}
+
+		archive.Close()
+
+		if diff := deep.Equal(got, tt.want); diff != nil {
+			t.Errorf("%s entries %v", tt.output, diff)
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte("files: [{source: LICENSE}]"), mod); err == nil ||
+		!strings.Contains(err.Error(), `unknown file field "source"`) {
+		t.Errorf("yaml.Unmarshal() error = %v", err)
+	}
+
+	mod.Files = []*ArchiveFile{{Dst: "{{OS}}/"}}
+	if err := mod.Validate(); err == nil || err.Error() != "files[0].src: required" {
+		t.Errorf("Tar.Validate() error = %v", err)
+	}
+}
@@ -6,21 +6,54 @@ import (
 
 	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
-	"github.com/magefile/mage/sh"
 )
 
 // UPX is a module for compressing executable binaries in a self-extracting
 // format using `upx` tool.
 type UPX struct {
-	// Builds specifies a build names to find related artifacts to
-	// modify.
-	Builds []string
-	// Skip specifies which os-arch items should be skipped
-	Skip []string
+	// Builds selects artifacts to modify, by their IDs, or by artifact
+	// filters.
+	Builds modules.Selector
+	// Skip specifies which os-arch items, or artifact filters should be
+	// skipped
+	Skip modules.Skip
 }
 
 func NewUPX() modules.Pluggable {
-	return &UPX{Builds: []string{"default"}}
+	return &UPX{Builds: modules.SelectIDs("default")}
+}
+
+// Describe documents UPX module
+func (*UPX) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Compresses executables in place with upx",
+		Fields: map[string]string{
+			"builds": "Artifact IDs, or artifact filters to be compressed",
+			"skip":   "OS-arch combinations, or artifact filters to be skipped",
+		},
+	}
+}
+
+// Validate checks upx settings
+func (archive *UPX) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", archive.Builds))
+	errs.Extend(archive.Builds.Validate("builds"))
+	errs.Extend(archive.Skip.Validate("skip"))
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs to be compressed
+func (archive *UPX) Consumes() []string {
+	return archive.Builds.IDs()
+}
+
+// Produces returns artifact IDs to be compressed, as they are modified
+// in place
+func (archive *UPX) Produces() []string {
+	return archive.Builds.IDs()
 }
 
 // Run calls upx on built artifacts, changing their artifact types
@@ -36,22 +69,69 @@ func (archive *UPX) Run(cx context.Context) error {
 		return err
 	}
 
-	artifactMap := context.Artifacts.OsArchByIDs(archive.Builds, archive.Skip)
-	if len(artifactMap) == 0 {
+	args := archive.files(context)
+	if len(args) == 0 {
 		return nil
 	}
 
-	args := []string{}
+	if err := modules.RunCommand(cx, nil, append([]string{upxCmd}, args...)...); err != nil {
+		return err
+	}
+
+	for _, artifact := range archive.artifacts(context) {
+		if err := artifact.Stat(); err != nil {
+			return err
+		}
 
-	for osarch := range artifactMap {
-		for _, artifact := range *artifactMap[osarch] {
-			args = append(args, artifact.Location)
+		if artifact.Extra == nil {
+			artifact.Extra = map[string]interface{}{}
 		}
+
+		artifact.Extra["compressor"] = "upx"
 	}
 
-	if err := sh.RunV(upxCmd, args...); err != nil {
+	return nil
+}
+
+// Plan describes the upx command to be run
+func (archive *UPX) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
 		return err
 	}
 
+	args := archive.files(context)
+	if len(args) == 0 {
+		return nil
+	}
+
+	plan.AddCommand(append([]string{"upx"}, args...)...)
+
+	for _, file := range args {
+		plan.AddFile(file)
+	}
+
 	return nil
 }
+
+func (archive *UPX) files(context *ctx.Context) []string {
+	files := []string{}
+
+	for _, artifact := range archive.artifacts(context) {
+		files = append(files, artifact.Location)
+	}
+
+	return files
+}
+
+// artifacts returns artifacts to be compressed, in OS-arch order
+func (archive *UPX) artifacts(context *ctx.Context) ctx.Artifacts {
+	artifactMap := context.ListArtifacts().OsArchBySelector(archive.Builds.Skipping(archive.Skip))
+	artifacts := ctx.Artifacts{}
+
+	for _, osarch := range sortedOsArchs(artifactMap) {
+		artifacts = append(artifacts, *artifactMap[osarch]...)
+	}
+
+	return artifacts
+}
@@ -0,0 +1,195 @@
+package modules
+
+import (
+	"context"
+	"path"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+type (
+	// Zip is a module for building archives from prior builds, in zip, or
+	// tar format, depending on the target OS
+	Zip struct {
+		// Builds selects artifacts to be added to the archive, by their
+		// IDs, or by artifact filters.
+		Builds modules.Selector
+		// CommonDir contains a common directory name for all files inside
+		// the archive. An empty CommonDir skips creating subdirectories.
+		// Default: `{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}`.
+		CommonDir string
+		// Compression specifies which compression should be applied to
+		// tar archives.
+		Compression Compression
+		// Files contains static files to be added to the archive. Entries
+		// are globs, or mappings of src, dst, mode, strip_parent, and skip.
+		// Default: `README*`.
+		Files []*ArchiveFile
+		// Format is the archive format of targets not listed in Formats:
+		// zip, or tar. Default: tar.
+		Format string
+		// Formats maps OS names, or patterns, to archive formats,
+		// overriding Format. Default: `{windows: zip}`.
+		Formats map[string]string
+		// ID contains the artifact's name used by later stages of the build
+		// pipeline. Archives, and Publishes may refer to this name for
+		// referencing build results.
+		// Default: "archive".
+		ID string
+		// Output is where the build writes its output. Default:
+		// `{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}{{.Ext}}`
+		// where `{{.Ext}}` is `.zip` for zip archives, and `.tar` with
+		// the compression's extension for tar archives.
+		Output string
+		// Skip specifies GOOS-GOArch combinations to be skipped.
+		// They are in `{{.Os}}-{{.Arch}}` format, or artifact filters.
+		// It filters builds to be included.
+		Skip modules.Skip
+		// ArchiveSettings are settings of archive entries.
+		ArchiveSettings `yaml:",inline"`
+	}
+)
+
+// nolint: gochecknoglobals
+var archiveFormats = []string{formatTar, formatZip}
+
+// nolint: gochecknoinits
+func init() {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "zip",
+		Factory: NewZip,
+	})
+}
+
+// NewZip is a Zip struct factory
+func NewZip() modules.Pluggable {
+	return &Zip{
+		Builds:      modules.SelectIDs("default"),
+		CommonDir:   "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}",
+		Compression: Compression{Compressor: &CompressNONE{}},
+		Files:       []*ArchiveFile{{Src: "README*"}},
+		Format:      formatTar,
+		Formats:     map[string]string{"windows": formatZip},
+		ID:          "archive",
+		Output:      "{{.ProjectName}}-{{.Version}}-{{OS}}-{{ArchName}}{{.Ext}}",
+		Skip:        modules.Skip{},
+	}
+}
+
+// Describe documents Zip module
+func (*Zip) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "Puts artifacts into a zip, or tar archive for each OS-arch combination",
+		Fields: map[string]string{
+			"builds":      "Artifact IDs, or artifact filters to be put into archives",
+			"commondir":   "Topmost directory name template inside archives",
+			"dir_mode":    "Mode of directory entries, like 0755",
+			"file_mode":   "Mode of file entries, like 0644, with execute permissions for executables",
+			"compression": "Compression format of tar archives, or a mapping of format, and level. xz levels select the dictionary size only",
+			"files":       "Static files to be added to archives: globs, or mappings of src, dst, mode, strip_parent, and skip",
+			"format":      "Archive format of OSes not in formats: zip, or tar",
+			"formats":     "Archive formats by OS names, or patterns",
+			"id":          "Resulting artifact ID",
+			"normalize":   "Sorts entries, owned by root, with SOURCE_DATE_EPOCH, or commit time as modification time",
+			"output":      "Archive file name template",
+			"skip":        "OS-arch combinations, or artifact filters to be skipped",
+			"tar_format":  "Format of tar archives: pax, gnu, or ustar",
+		},
+	}
+}
+
+// Validate checks archive settings
+func (mod *Zip) Validate() error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireSelector("builds", mod.Builds))
+	errs.Extend(mod.Builds.Validate("builds"))
+	errs.Extend(mod.Skip.Validate("skip"))
+	errs.Add(modules.RequireField("id", mod.ID))
+	errs.Add(modules.RequireField("output", mod.Output))
+	errs.Add(mod.ArchiveSettings.validate())
+	errs.Extend(validateFiles(mod.Files))
+	errs.Add(validateChoice("format", mod.Format, archiveFormats))
+
+	for _, goos := range mod.osPatterns() {
+		if _, err := path.Match(goos, ""); err != nil {
+			errs.Add(modules.NewFieldError("formats", "invalid pattern %q", goos))
+		}
+
+		errs.Add(validateChoice("formats", mod.Formats[goos], archiveFormats))
+	}
+
+	return errs.Err()
+}
+
+// Consumes returns artifact IDs put into archives
+func (mod *Zip) Consumes() []string {
+	return mod.Builds.IDs()
+}
+
+// Produces returns the artifact ID of archives
+func (mod *Zip) Produces() []string {
+	return []string{mod.ID}
+}
+
+func (mod *Zip) Run(cx context.Context) error {
+	return runArchives(cx, mod.Builds.Skipping(mod.Skip), mod.singleTarget)
+}
+
+// Plan describes archives to be created, and predicts their artifacts
+func (mod *Zip) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	return planArchives(cx, plan, mod.Builds.Skipping(mod.Skip), mod.singleTarget)
+}
+
+// osPatterns returns keys of Formats, exact names last, in alphabetical
+// order
+func (mod *Zip) osPatterns() []string {
+	keys := make([]string, 0, len(mod.Formats))
+
+	for key := range mod.Formats {
+		keys = append(keys, key)
+	}
+
+	sortPatternsFirst(keys)
+
+	return keys
+}
+
+// format returns the archive format of an OS. Exact names take precedence
+// over patterns.
+func (mod *Zip) format(goos string) string {
+	format := mod.Format
+
+	for _, pattern := range mod.osPatterns() {
+		if ok, _ := path.Match(pattern, goos); ok {
+			format = mod.Formats[pattern]
+		}
+	}
+
+	if format == "" {
+		return formatTar
+	}
+
+	return format
+}
+
+func (mod *Zip) singleTarget(cx context.Context, artifacts *ctx.Artifacts) (*archiveSingleTarget, error) {
+	format := mod.format((*artifacts)[0].OsArch.OS)
+	ext := ".zip"
+
+	if format == formatTar {
+		ext = ".tar" + mod.Compression.Extension()
+	}
+
+	return newArchiveTarget(cx, &archiveSingleTarget{
+		CommonDir:   mod.CommonDir,
+		Compression: mod.Compression,
+		Files:       mod.Files,
+		Format:      format,
+		ID:          mod.ID,
+		Output:      mod.Output,
+		Settings:    mod.ArchiveSettings,
+	}, ext, artifacts)
+}
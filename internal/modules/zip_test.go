@@ -0,0 +1,106 @@
+package modules
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+)
+
+func TestZip_format(t *testing.T) {
+	mod := NewZip().(*Zip)
+	mod.Formats["*bsd"] = formatZip
+	mod.Formats["openbsd"] = formatTar
+
+	got := map[string]string{}
+	for _, goos := range []string{"linux", "windows", "freebsd", "openbsd"} {
+		got[goos] = mod.format(goos)
+	}
+
+	want := map[string]string{"linux": "tar", "windows": "zip", "freebsd": "zip", "openbsd": "tar"}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Zip.format() %v", diff)
+	}
+
+	mod.Formats["darwin"] = "dmg"
+	if err := mod.Validate(); err == nil || err.Error() != `formats: invalid value "dmg", valid values are tar, zip` {
+		t.Errorf("Zip.Validate() error = %v", err)
+	}
+}
+
+func TestZip_Run(t *testing.T) {
+	dir := t.TempDir()
+	binary := path.Join(dir, "app.exe")
+
+	if err := os.WriteFile(binary, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cx := ctx.New(context.Background())
+
+	shipContext, err := ctx.GetShipContext(cx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipContext.ProjectName = "app"
+	shipContext.Version = "1.0"
+	shipContext.TargetDir = dir
+	shipContext.Env.Set(ctx.SourceDateEpochVar, "1700000000")
+
+	for _, osarch := range []*ctx.OsArch{{OS: "windows", Arch: "amd64"}, {OS: "linux", Arch: "amd64"}} {
+		shipContext.Artifacts.Add(&ctx.Artifact{
+			ID:       "default",
+			Filename: "app.exe",
+			Location: binary,
+			Kind:     ctx.KindBinary,
+			OsArch:   osarch,
+		})
+	}
+
+	mod := NewZip().(*Zip)
+	mod.Files = nil
+
+	if err := mod.Run(cx); err != nil {
+		t.Fatalf("Zip.Run() unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, art := range *shipContext.Artifacts.ByID("archive") {
+		got = append(got, art.Filename)
+	}
+
+	if diff := deep.Equal(got, []string{"app-1.0-linux-amd64.tar", "app-1.0-windows-amd64.zip"}); diff != nil {
+		t.Errorf("Zip.Run() artifacts %v", diff)
+	}
+
+	reader, err := zip.OpenReader(path.Join(dir, "app-1.0-windows-amd64.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	entries := map[string]os.FileMode{}
+	modTime := time.Unix(1700000000, 0)
+
+	for _, file := range reader.File {
+		entries[file.Name] = file.Mode()
+
+		if !file.Modified.Equal(modTime) {
+			t.Errorf("%s: modified = %v, want %v", file.Name, file.Modified, modTime)
+		}
+	}
+
+	if len(entries) != 2 || !entries["app-1.0-windows-amd64/"].IsDir() {
+		t.Errorf("zip entries = %v, want a common directory, and app.exe", entries)
+	}
+
+	if mode := entries["app-1.0-windows-amd64/app.exe"]; mode != 0o755 {
+		t.Errorf("app.exe mode = %v, want -rwxr-xr-x", mode)
+	}
+}
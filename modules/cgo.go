@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"sort"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// nolint: gochecknoglobals
+var cToolchainResolvers map[string]CToolchainResolver
+
+type (
+	// CToolchain is a C toolchain of cgo builds. Its fields are set as
+	// environment variables of builds, if they are not empty.
+	CToolchain struct {
+		// AR is the archiver command, set as AR
+		AR string
+		// CC is the C compiler command, set as CC
+		CC string
+		// CXX is the C++ compiler command, set as CXX
+		CXX string
+		// CFlags are flags of the C compiler, set as CGO_CFLAGS
+		CFlags string `yaml:"cflags"`
+		// LDFlags are flags of the linker, set as CGO_LDFLAGS
+		LDFlags string `yaml:"ldflags"`
+	}
+
+	// CToolchainResolver finds C toolchains of build targets. Resolvers
+	// register themselves with RegisterCToolchainResolver during init().
+	CToolchainResolver interface {
+		// Resolve returns the C toolchain of a target, or an error if
+		// the target is not supported.
+		Resolve(osarch *ctx.OsArch) (*CToolchain, error)
+	}
+)
+
+// RegisterCToolchainResolver registers a C toolchain resolver by name
+func RegisterCToolchainResolver(name string, resolver CToolchainResolver) {
+	if cToolchainResolvers == nil {
+		cToolchainResolvers = make(map[string]CToolchainResolver)
+	}
+
+	cToolchainResolvers[name] = resolver
+}
+
+// LookupCToolchainResolver returns a C toolchain resolver by name
+func LookupCToolchainResolver(name string) (CToolchainResolver, bool) {
+	resolver, ok := cToolchainResolvers[name]
+
+	return resolver, ok
+}
+
+// CToolchainResolvers returns names of registered C toolchain resolvers
+// in alphabetical order
+func CToolchainResolvers() []string {
+	names := make([]string, 0, len(cToolchainResolvers))
+
+	for name := range cToolchainResolvers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Override returns the toolchain, with fields replaced by non-empty
+// fields of other
+func (tc CToolchain) Override(other *CToolchain) CToolchain {
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&tc.AR, other.AR},
+		{&tc.CC, other.CC},
+		{&tc.CXX, other.CXX},
+		{&tc.CFlags, other.CFlags},
+		{&tc.LDFlags, other.LDFlags},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+
+	return tc
+}
+
+// Commands returns commands of the toolchain by their environment
+// variable names, skipping empty ones
+func (tc *CToolchain) Commands() map[string]string {
+	commands := map[string]string{}
+
+	for key, value := range map[string]string{"AR": tc.AR, "CC": tc.CC, "CXX": tc.CXX} {
+		if value != "" {
+			commands[key] = value
+		}
+	}
+
+	return commands
+}
+
+// Env returns environment variables of the toolchain, in KEY=value
+// format, skipping empty ones
+func (tc *CToolchain) Env() []string {
+	env := []string{}
+
+	for _, item := range []struct {
+		key   string
+		value string
+	}{
+		{"CC", tc.CC},
+		{"CXX", tc.CXX},
+		{"AR", tc.AR},
+		{"CGO_CFLAGS", tc.CFlags},
+		{"CGO_LDFLAGS", tc.LDFlags},
+	} {
+		if item.value != "" {
+			env = append(env, item.key+"="+item.value)
+		}
+	}
+
+	return env
+}
+
+// IsEmpty returns true if no fields are set
+func (tc *CToolchain) IsEmpty() bool {
+	return len(tc.Env()) == 0
+}
@@ -0,0 +1,195 @@
+package modules
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: gochecknoglobals
+var compressorRegistry map[string]*CompressorRegistration
+
+type (
+	// Compressor defines compression interface
+	Compressor interface {
+		fmt.Stringer
+		// Extension returns the file name extension of the format, like
+		// `.gz`.
+		Extension() string
+		// Writer returns a writer compressing into w. Closing it doesn't
+		// close w.
+		Writer(w io.Writer) io.WriteCloser
+	}
+
+	// CheckedCompressor is a Compressor, which reports errors of setting
+	// up compression, instead of failing on the first write.
+	CheckedCompressor interface {
+		Compressor
+		// NewWriter returns a writer compressing into w. Closing it
+		// doesn't close w.
+		NewWriter(w io.Writer) (io.WriteCloser, error)
+	}
+
+	// CompressorFactory creates a compressor of a compression level.
+	// Level 0 selects the format's default level.
+	CompressorFactory func(level int) (Compressor, error)
+
+	// CompressorRegistration is a compression format registration entry
+	CompressorRegistration struct {
+		// Name is the name of the format in configuration, like `gzip`.
+		Name string
+		// Aliases are alternative names of the format, like `gz`.
+		Aliases []string
+		// Factory creates compressors of the format.
+		Factory CompressorFactory
+	}
+
+	// Compression is a YAML representation of a compression format. It is
+	// either a format name, like `gzip`, or a mapping of the format name,
+	// and the compression level, like `{format: zstd, level: 19}`.
+	Compression struct {
+		Compressor
+		// Level is the compression level. Level 0 is the format's
+		// default level.
+		Level int
+	}
+)
+
+// RegisterCompressor allows compression formats to register themselves
+// during init(), by their names, and aliases.
+func RegisterCompressor(registration *CompressorRegistration) {
+	if compressorRegistry == nil {
+		compressorRegistry = make(map[string]*CompressorRegistration)
+	}
+
+	compressorRegistry[registration.Name] = registration
+
+	for _, alias := range registration.Aliases {
+		compressorRegistry[alias] = registration
+	}
+}
+
+// LookupCompressor returns the factory of a compression format by its
+// name, or alias
+func LookupCompressor(name string) (CompressorFactory, bool) {
+	registration, ok := compressorRegistry[name]
+	if !ok {
+		return nil, false
+	}
+
+	return registration.Factory, true
+}
+
+// CompressorNames returns names, and aliases of registered compression
+// formats, in alphabetical order
+func CompressorNames() []string {
+	names := make([]string, 0, len(compressorRegistry))
+
+	for name := range compressorRegistry {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewCompression returns a compression of a registered format
+func NewCompression(format string, level int) (Compression, error) {
+	factory, ok := LookupCompressor(format)
+	if !ok {
+		return Compression{}, fmt.Errorf("invalid compression format: `%s`", format)
+	}
+
+	compressor, err := factory(level)
+	if err != nil {
+		return Compression{}, err
+	}
+
+	return Compression{Compressor: compressor, Level: level}, nil
+}
+
+// NewWriter returns a writer compressing into w. It reports setup errors
+// of CheckedCompressors, and it falls back to Writer of other compressors.
+func (c Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if checked, ok := c.Compressor.(CheckedCompressor); ok {
+		return checked.NewWriter(w)
+	}
+
+	return c.Compressor.Writer(w), nil
+}
+
+// UnmarshalYAML detects compression format, and level
+func (c *Compression) UnmarshalYAML(node *yaml.Node) error {
+	var (
+		format string
+		level  int
+	)
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if err := node.Decode(&format); err != nil {
+			return NewConfigError(node, fmt.Errorf("compression cannot be decoded: %w", err))
+		}
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key, val := node.Content[idx], node.Content[idx+1]
+
+			var err error
+
+			switch key.Value {
+			case "format":
+				err = val.Decode(&format)
+			case "level":
+				err = val.Decode(&level)
+			default:
+				return NewConfigError(key, fmt.Errorf("unknown compression field %q, expecting format, or level", key.Value))
+			}
+
+			if err != nil {
+				return NewConfigError(val, fmt.Errorf("compression %s cannot be decoded: %w", key.Value, err))
+			}
+		}
+	default:
+		return NewConfigError(node, fmt.Errorf("compression is `%v`, not scalar, or mapping", node.Kind))
+	}
+
+	compression, err := NewCompression(format, level)
+	if err != nil {
+		return NewConfigError(node, err)
+	}
+
+	*c = compression
+
+	return nil
+}
+
+// MarshalYAML returns the compression format's name, or a mapping of its
+// name, and level, if the level is set
+func (c Compression) MarshalYAML() (interface{}, error) {
+	name := "none"
+	if c.Compressor != nil {
+		name = strings.ToLower(c.Compressor.String())
+	}
+
+	if c.Level == 0 {
+		return name, nil
+	}
+
+	return map[string]interface{}{"format": name, "level": c.Level}, nil
+}
+
+// Enum returns valid compression formats
+func (Compression) Enum() []string {
+	return CompressorNames()
+}
+
+// MappingKeys returns keys of the mapping form
+func (Compression) MappingKeys() []string {
+	return []string{"format", "level"}
+}
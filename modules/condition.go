@@ -0,0 +1,52 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ParseCondition checks the syntax of a condition, without evaluating it.
+func ParseCondition(text string) error {
+	_, err := (&TemplateData{}).template("if", text)
+
+	return err
+}
+
+// EvalCondition evaluates a condition in template format against
+// ctx.Context. A condition is false if it renders to an empty string,
+// "0", "false", "no", or "off". Empty conditions are true. It returns the
+// reason if the condition is false.
+func EvalCondition(cx context.Context, text string) (bool, string, error) {
+	if text == "" {
+		return true, "", nil
+	}
+
+	td, err := NewTemplate(cx)
+	if err != nil {
+		return false, "", err
+	}
+
+	result, err := td.Parse("if", text)
+	if err != nil {
+		return false, "", fmt.Errorf("evaluating condition: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(result)) {
+	case "", "0", "false", "no", "off", "<no value>":
+		return false, fmt.Sprintf("condition %q evaluated to %q", text, result), nil
+	}
+
+	return true, "", nil
+}
+
+// Enabled evaluates the module's condition (see Module.If) against
+// ctx.Context. It returns the reason if the module should be skipped.
+func (mod *Module) Enabled(cx context.Context) (bool, string, error) {
+	enabled, reason, err := EvalCondition(cx, mod.If)
+	if err != nil {
+		return false, "", fmt.Errorf("%s: %w", mod.Type, err)
+	}
+
+	return enabled, reason, nil
+}
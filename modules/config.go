@@ -0,0 +1,100 @@
+package modules
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Validator is an optional interface of a Pluggable, checking its
+	// configuration right after it is loaded. Problems of specific fields
+	// should be reported as FieldError, to allow reporting their exact
+	// position. Multiple problems can be reported in Errors.
+	Validator interface {
+		Validate() error
+	}
+
+	// Enumerator is an optional interface of configuration field types
+	// decoding themselves, listing their valid values. It is used for
+	// documentation purposes.
+	Enumerator interface {
+		Enum() []string
+	}
+
+	// MappingForm is an optional interface of configuration field types
+	// decoding themselves, which accept a mapping besides scalar values,
+	// listing keys of the mapping. It is used for documentation purposes.
+	MappingForm interface {
+		MappingKeys() []string
+	}
+
+	// FieldError is a problem with a single configuration field of a
+	// module. Field is the YAML key of the field.
+	FieldError struct {
+		Field string
+		Err   error
+	}
+
+	// ConfigError is a configuration problem at a specific position of
+	// the YAML source. File is optional, it is set when configuration is
+	// composed from multiple files.
+	ConfigError struct {
+		File   string
+		Line   int
+		Column int
+		Err    error
+	}
+)
+
+// NewFieldError returns a FieldError for a field with a formatted message
+func NewFieldError(field, format string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// NewConfigError returns a ConfigError at the position of a YAML node
+func NewConfigError(node *yaml.Node, err error) *ConfigError {
+	return &ConfigError{Line: node.Line, Column: node.Column, Err: err}
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+
+	if e.File != "" {
+		return fmt.Sprintf("%s: line %d, column %d: %v", e.File, e.Line, e.Column, e.Err)
+	}
+
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// RequireField returns a FieldError if value is empty
+func RequireField(field, value string) error {
+	if value == "" {
+		return NewFieldError(field, "required")
+	}
+
+	return nil
+}
+
+// RequireList returns a FieldError if list is empty
+func RequireList(field string, list []string) error {
+	if len(list) == 0 {
+		return NewFieldError(field, "must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package modules
+
+import "strings"
+
+// Errors is a collection of errors, reported as a single error. It
+// allows modules to carry on processing after a failure, and report
+// all problems at once.
+type Errors []error
+
+// Add appends an error to the collection. Nil errors are ignored.
+func (errs *Errors) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	*errs = append(*errs, err)
+}
+
+// Extend adds an error to the collection. If the error is a collection
+// itself, its items are added one by one.
+func (errs *Errors) Extend(err error) {
+	if items, ok := err.(Errors); ok {
+		*errs = append(*errs, items...)
+
+		return
+	}
+
+	errs.Add(err)
+}
+
+// Err returns nil if there are no errors collected, or the collection
+// itself otherwise.
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// Error returns all collected error messages, one per line.
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
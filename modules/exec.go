@@ -0,0 +1,126 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/julian7/withenv"
+)
+
+// errNoCommand is returned when a command is run without its name
+var errNoCommand = errors.New("no command provided")
+
+// RunCommand runs an external command, with its output sent to standard
+// output and error, and reports it to the observer of the context. The
+// environment is taken from env, or from the process, if env is nil.
+// Arguments are expanded with the same environment.
+//
+// The command, with all the processes it started, is killed when the
+// context is done. If standard input is a terminal, the command stays in
+// the foreground process group, to be able to prompt the user, and only
+// the command itself is killed.
+func RunCommand(cx context.Context, env *withenv.Env, args ...string) error {
+	if len(args) == 0 {
+		return errNoCommand
+	}
+
+	return observeCommand(cx, args, func() error {
+		cmd := command(env, args)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		return runContext(cx, cmd)
+	})
+}
+
+// CommandOutput runs an external command, returns its output without
+// trailing newlines, and reports it to the observer of the context. The
+// command is killed when the context is done.
+func CommandOutput(cx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", errNoCommand
+	}
+
+	var out bytes.Buffer
+
+	err := observeCommand(cx, args, func() error {
+		cmd := command(nil, args)
+		cmd.Stdout = &out
+		cmd.Stderr = os.Stderr
+
+		return runContext(cx, cmd)
+	})
+
+	return strings.TrimRight(out.String(), "\r\n"), err
+}
+
+// command sets up a command with an environment
+func command(env *withenv.Env, args []string) *exec.Cmd {
+	expand, environ := os.ExpandEnv, os.Environ()
+	if env != nil {
+		expand, environ = env.Expand, env.Environ()
+	}
+
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		expanded = append(expanded, expand(arg))
+	}
+
+	cmd := exec.Command(expanded[0], expanded[1:]...) // nolint: gosec
+	cmd.Env = environ
+
+	return cmd
+}
+
+// runContext runs a command, killing its process group when the context
+// is done. It returns the context's error, if the command was killed.
+func runContext(cx context.Context, cmd *exec.Cmd) error {
+	if err := cx.Err(); err != nil {
+		return err
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-cx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	err := cmd.Wait()
+	if err != nil && cx.Err() != nil {
+		return fmt.Errorf("%w (%s)", cx.Err(), err)
+	}
+
+	return err
+}
+
+func observeCommand(cx context.Context, args []string, run func() error) error {
+	start := time.Now()
+	err := run()
+
+	ObserverFrom(cx).Notify(&Event{
+		Kind:     CommandExecuted,
+		Duration: time.Since(start),
+		Err:      err,
+		Command:  append([]string{}, args...),
+	})
+
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package modules
+
+import "os"
+
+// isTerminal returns false, as terminals are not detected on this platform
+func isTerminal(*os.File) bool {
+	return false
+}
@@ -0,0 +1,25 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package modules
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns true if the file is a terminal, with a foreground
+// process group
+func isTerminal(file *os.File) bool {
+	var pgrp int32
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		file.Fd(),
+		uintptr(syscall.TIOCGPGRP),
+		uintptr(unsafe.Pointer(&pgrp)), // nolint: gosec
+	)
+
+	return errno == 0
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package modules
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group, to allow
+// killing all the processes it starts. Commands reading from a terminal
+// are kept in the foreground process group, otherwise they would be
+// stopped when prompting the user (eg. for a password).
+func setProcessGroup(cmd *exec.Cmd) {
+	if file, ok := cmd.Stdin.(*os.File); ok && isTerminal(file) {
+		return
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the command, and all the processes in its
+// process group, if it has its own
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		_ = cmd.Process.Kill()
+
+		return
+	}
+
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package modules
+
+import "os/exec"
+
+// setProcessGroup does nothing on Windows
+func setProcessGroup(*exec.Cmd) {}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
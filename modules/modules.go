@@ -4,8 +4,8 @@ package modules
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 )
 
@@ -15,24 +15,69 @@ type (
 		Run(context.Context) error
 	}
 
-	// Module is a single module, specifying its type and its Pluggable
+	// Consumer is an optional interface of a Pluggable, declaring which
+	// artifact IDs the module reads. Stages use it to order modules.
+	Consumer interface {
+		Consumes() []string
+	}
+
+	// Producer is an optional interface of a Pluggable, declaring which
+	// artifact IDs the module creates, or modifies in place. Stages use it
+	// to order modules. The only ID a new instance produces is the
+	// module's default ID, matching configuration overrides without an
+	// explicit `id` (see DefaultID).
+	Producer interface {
+		Produces() []string
+	}
+
+	// Describer is an optional interface of a Pluggable, providing
+	// human readable documentation of the module.
+	Describer interface {
+		Describe() *Description
+	}
+
+	// Description documents a module, and its configuration fields
+	Description struct {
+		// Summary is a short description of the module
+		Summary string
+		// Fields contains descriptions of configuration fields, by
+		// their YAML keys
+		Fields map[string]string
+	}
+
+	// Module is a single module, specifying its type and its Pluggable.
+	// If is an optional condition in template format: the module is
+	// skipped if it renders to an empty string, "0", "false", "no", or
+	// "off". Timeout is an optional time limit of running the module.
 	Module struct {
-		Type string
+		Type    string
+		If      string
+		Timeout time.Duration
 		Pluggable
 	}
 )
 
-// Run executes a module, and measures its wallclock time spent
+// Run executes a module, cancelling its context after Timeout, if set.
+// Errors of modules timed out, or interrupted by cancelling the context,
+// are reported as such. Its events are reported by the stage running it.
 func (mod *Module) Run(ctx context.Context) error {
-	log.Printf("----> %s", mod.Type)
-
-	start := time.Now()
+	if mod.Timeout > 0 {
+		var cancel context.CancelFunc
 
-	if err := mod.Pluggable.Run(ctx); err != nil {
-		return fmt.Errorf("%s: %w", mod.Type, err)
+		ctx, cancel = context.WithTimeout(ctx, mod.Timeout)
+		defer cancel()
 	}
 
-	log.Printf("<---- %s done in %s", mod.Type, time.Since(start))
+	err := mod.Pluggable.Run(ctx)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && mod.Timeout > 0:
+		return fmt.Errorf("%s: timed out after %s: %w", mod.Type, mod.Timeout, err)
+	case ctx.Err() != nil:
+		return fmt.Errorf("%s: interrupted: %w", mod.Type, err)
+	}
 
-	return nil
+	return fmt.Errorf("%s: %w", mod.Type, err)
 }
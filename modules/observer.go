@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+// Event kinds
+const (
+	StageStarted EventKind = iota
+	StageFinished
+	StageSkipped
+	ModuleStarted
+	ModuleFinished
+	ModuleFailed
+	ModuleSkipped
+	ArtifactAdded
+	CommandExecuted
+)
+
+type (
+	// Observer receives events of a pipeline run. Events might be sent
+	// from multiple goroutines, therefore observers must be safe for
+	// concurrent use.
+	Observer interface {
+		Notify(*Event)
+	}
+
+	// ObserverFunc is a function implementing Observer
+	ObserverFunc func(*Event)
+
+	// Observers sends events to multiple observers, in order
+	Observers []Observer
+
+	// LogObserver logs events of a pipeline run. It is the default
+	// observer.
+	LogObserver struct{}
+
+	// EventKind is the type of an Event
+	EventKind int
+
+	// Event is something happening in a pipeline run. Fields not related
+	// to the event's kind are empty.
+	Event struct {
+		Kind EventKind
+		// Stage is the name of the stage, for stage and module events
+		Stage string
+		// Module is the type of the module, for module events
+		Module string
+		// Duration is the time spent, for finished or failed stages,
+		// modules, and commands
+		Duration time.Duration
+		// Err is the error of failed stages, modules, and commands
+		Err error
+		// Reason explains why a stage or a module is skipped
+		Reason string
+		// Artifact is the artifact added
+		Artifact *ctx.Artifact
+		// Command is the command executed, with its arguments
+		Command []string
+	}
+
+	observerKey struct{}
+)
+
+// Notify calls the function itself
+func (fn ObserverFunc) Notify(event *Event) {
+	fn(event)
+}
+
+// Notify sends the event to all observers
+func (observers Observers) Notify(event *Event) {
+	for _, observer := range observers {
+		observer.Notify(event)
+	}
+}
+
+// Notify logs the event
+func (LogObserver) Notify(event *Event) {
+	switch event.Kind {
+	case StageStarted:
+		log.Printf("====> %s", strings.ToUpper(event.Stage))
+	case StageFinished:
+		if event.Err == nil {
+			log.Printf("<==== %s done in %s", strings.ToUpper(event.Stage), event.Duration)
+		}
+	case StageSkipped:
+		log.Printf("SKIPPED: %s", event.Reason)
+	case ModuleStarted:
+		log.Printf("----> %s", event.Module)
+	case ModuleFinished:
+		log.Printf("<---- %s done in %s", event.Module, event.Duration)
+	case ModuleFailed:
+		log.Printf("<---- %s failed in %s", event.Module, event.Duration)
+	case ModuleSkipped:
+		log.Printf("----- %s skipped: %s", event.Module, event.Reason)
+	case ArtifactAdded:
+		art := event.Artifact
+		log.Printf("      storing artifact %s as %s (%s)", art.Filename, art.ID, art.OsArch.String())
+	case CommandExecuted:
+	}
+}
+
+// String returns the name of the event kind
+func (kind EventKind) String() string {
+	names := []string{
+		"stage started",
+		"stage finished",
+		"stage skipped",
+		"module started",
+		"module finished",
+		"module failed",
+		"module skipped",
+		"artifact added",
+		"command executed",
+	}
+
+	if kind < 0 || int(kind) >= len(names) {
+		return "unknown"
+	}
+
+	return names[kind]
+}
+
+// WithObserver returns a context, carrying an observer for modules and
+// stages to report their events to.
+func WithObserver(cx context.Context, observer Observer) context.Context {
+	return context.WithValue(cx, observerKey{}, observer)
+}
+
+// ObserverFrom returns the observer carried by the context, or
+// LogObserver if none.
+func ObserverFrom(cx context.Context) Observer {
+	if observer, ok := cx.Value(observerKey{}).(Observer); ok {
+		return observer
+	}
+
+	return LogObserver{}
+}
@@ -0,0 +1,195 @@
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+)
+
+type (
+	// Planner is an optional interface of a Pluggable, describing what the
+	// module would do in a real run, without doing it. Planners should
+	// register predicted artifacts with ModulePlan.AddArtifact, allowing
+	// later modules to plan against them.
+	Planner interface {
+		Plan(context.Context, *ModulePlan) error
+	}
+
+	// Plan describes what a pipeline run would do
+	Plan struct {
+		Stages []*StagePlan `json:"stages"`
+	}
+
+	// StagePlan describes what a stage would do
+	StagePlan struct {
+		Name    string        `json:"name"`
+		Skipped bool          `json:"skipped,omitempty"`
+		Modules []*ModulePlan `json:"modules,omitempty"`
+	}
+
+	// ModulePlan describes what a single module would do
+	ModulePlan struct {
+		Type string `json:"type"`
+		// Skipped contains the reason if the module's condition is false
+		Skipped string `json:"skipped,omitempty"`
+		// Unplanned is set for modules not implementing Planner
+		Unplanned   bool               `json:"unplanned,omitempty"`
+		Files       []string           `json:"files,omitempty"`
+		Commands    []string           `json:"commands,omitempty"`
+		RemoteCalls []string           `json:"remote_calls,omitempty"`
+		Artifacts   []*PlannedArtifact `json:"artifacts,omitempty"`
+	}
+
+	// PlannedArtifact is an artifact a module would register
+	PlannedArtifact struct {
+		ID       string `json:"id"`
+		Filename string `json:"filename"`
+		Location string `json:"location"`
+		OsArch   string `json:"osarch"`
+	}
+)
+
+// Plan describes a module's actions into a ModulePlan. Modules not
+// implementing Planner are flagged as unplanned.
+func (mod *Module) Plan(cx context.Context, plan *ModulePlan) error {
+	planner, ok := mod.Pluggable.(Planner)
+	if !ok {
+		plan.Unplanned = true
+
+		return nil
+	}
+
+	if err := planner.Plan(cx, plan); err != nil {
+		return fmt.Errorf("%s: %w", mod.Type, err)
+	}
+
+	return nil
+}
+
+// AddFile records a file the module would create or modify
+func (plan *ModulePlan) AddFile(filename string) {
+	plan.Files = append(plan.Files, filename)
+}
+
+// AddCommand records a command the module would execute. Arguments are
+// quoted if necessary.
+func (plan *ModulePlan) AddCommand(args ...string) {
+	quoted := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = strconv.Quote(arg)
+		}
+
+		quoted = append(quoted, arg)
+	}
+
+	plan.Commands = append(plan.Commands, strings.Join(quoted, " "))
+}
+
+// AddRemoteCall records a call the module would make to a remote service
+func (plan *ModulePlan) AddRemoteCall(format string, args ...interface{}) {
+	plan.RemoteCalls = append(plan.RemoteCalls, fmt.Sprintf(format, args...))
+}
+
+// AddArtifact records an artifact the module would create, and registers it
+// into ctx.Context, for later modules to plan with.
+func (plan *ModulePlan) AddArtifact(cx context.Context, artifact *ctx.Artifact) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	plan.Artifacts = append(plan.Artifacts, &PlannedArtifact{
+		ID:       artifact.ID,
+		Filename: artifact.Filename,
+		Location: artifact.Location,
+		OsArch:   artifact.OsArch.String(),
+	})
+
+	context.AddArtifact(artifact)
+
+	return nil
+}
+
+// WriteText writes a human readable representation of the plan
+func (plan *Plan) WriteText(w io.Writer) error {
+	var err error
+
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	for _, stage := range plan.Stages {
+		if stage.Skipped {
+			printf("%s: skipped\n", stage.Name)
+
+			continue
+		}
+
+		printf("%s:\n", stage.Name)
+
+		for _, mod := range stage.Modules {
+			if mod.Skipped != "" {
+				printf("  - %s (skipped: %s)\n", mod.Type, mod.Skipped)
+
+				continue
+			}
+
+			if mod.Unplanned {
+				printf("  - %s (no plan available)\n", mod.Type)
+
+				continue
+			}
+
+			printf("  - %s\n", mod.Type)
+
+			for _, item := range []struct {
+				title string
+				items []string
+			}{
+				{"run", mod.Commands},
+				{"write", mod.Files},
+				{"call", mod.RemoteCalls},
+			} {
+				for _, line := range item.items {
+					printf("      %s: %s\n", item.title, line)
+				}
+			}
+
+			for _, art := range mod.Artifacts {
+				printf("      artifact: %s: %s (%s)\n", art.ID, art.Filename, art.OsArch)
+			}
+		}
+	}
+
+	return err
+}
+
+// WriteJSON writes the plan in JSON format
+func (plan *Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(plan)
+}
+
+// Write writes the plan in the specified format, which is either "text"
+// or "json".
+func (plan *Plan) Write(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		return plan.WriteText(w)
+	case "json":
+		return plan.WriteJSON(w)
+	}
+
+	return fmt.Errorf("invalid plan format: `%s`", format)
+}
@@ -1,9 +1,12 @@
 package modules
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // nolint: gochecknoglobals
-var modRegistry map[string]PluggableFactory
+var modRegistry map[string]*ModuleRegistration
 
 type (
 	// PluggableFactory is a method, which yields a Pluggable
@@ -32,10 +35,51 @@ func (mod *ModuleRegistration) Kind() string {
 // by providing a definition of type ModuleRegistration.
 func RegisterModule(definition *ModuleRegistration) {
 	if modRegistry == nil {
-		modRegistry = make(map[string]PluggableFactory)
+		modRegistry = make(map[string]*ModuleRegistration)
+	}
+
+	modRegistry[definition.Kind()] = definition
+}
+
+// Registrations returns all registered modules, ordered by their kinds
+func Registrations() []*ModuleRegistration {
+	registrations := make([]*ModuleRegistration, 0, len(modRegistry))
+
+	for _, registration := range modRegistry {
+		registrations = append(registrations, registration)
+	}
+
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Kind() < registrations[j].Kind()
+	})
+
+	return registrations
+}
+
+// DefaultID returns the default artifact ID of a module registered for a
+// stage, or for all stages: the only artifact ID a new instance of the
+// module produces (see Producer). It returns an empty string if the
+// module is not registered, or it has no single default ID.
+func DefaultID(stage, typ string) string {
+	factory, ok := LookupModule(fmt.Sprintf("%s:%s", stage, typ))
+	if !ok {
+		factory, ok = LookupModule(fmt.Sprintf("*:%s", typ))
+	}
+
+	if !ok {
+		return ""
+	}
+
+	producer, ok := factory().(Producer)
+	if !ok {
+		return ""
+	}
+
+	if ids := producer.Produces(); len(ids) == 1 {
+		return ids[0]
 	}
 
-	modRegistry[definition.Kind()] = definition.Factory
+	return ""
 }
 
 // LookupModule returns a PluggableFactory based on its Kind
@@ -43,7 +87,7 @@ func RegisterModule(definition *ModuleRegistration) {
 func LookupModule(kind string) (PluggableFactory, bool) {
 	mod, ok := modRegistry[kind]
 	if ok {
-		return mod, true
+		return mod.Factory, true
 	}
 
 	return nil, false
@@ -0,0 +1,53 @@
+package modules_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julian7/goshipdone/modules"
+)
+
+type testProducerModule struct {
+	ids []string
+}
+
+func (mod *testProducerModule) Produces() []string {
+	return mod.ids
+}
+
+func (*testProducerModule) Run(context.Context) error {
+	return nil
+}
+
+func TestDefaultID(t *testing.T) {
+	for _, registration := range []*modules.ModuleRegistration{
+		{Stage: "build", Type: "single", Factory: func() modules.Pluggable {
+			return &testProducerModule{ids: []string{"build"}}
+		}},
+		{Stage: "*", Type: "single", Factory: func() modules.Pluggable {
+			return &testProducerModule{ids: []string{"any"}}
+		}},
+		{Stage: "build", Type: "multiple", Factory: func() modules.Pluggable {
+			return &testProducerModule{ids: []string{"first", "second"}}
+		}},
+	} {
+		modules.RegisterModule(registration)
+	}
+
+	tests := []struct {
+		stage string
+		typ   string
+		want  string
+	}{
+		{"build", "single", "build"},
+		{"publish", "single", "any"},
+		{"build", "multiple", ""},
+		{"build", "unknown", ""},
+	}
+
+	for _, tt := range tests {
+		if got := modules.DefaultID(tt.stage, tt.typ); got != tt.want {
+			t.Errorf("DefaultID(%q, %q) = %q, want %q", tt.stage, tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"time"
+)
+
+// Retry classes, selecting which errors are retried
+const (
+	// RetryNetwork covers connection problems, and timeouts of remote calls
+	RetryNetwork = "network"
+	// RetryServer covers HTTP 5xx responses
+	RetryServer = "server"
+	// RetryRateLimit covers rate limit responses, honoring the delay the
+	// server asks for
+	RetryRateLimit = "rate_limit"
+	// RetryCommand covers external commands exiting with an error. It
+	// is not retried by default, as commands mostly fail permanently.
+	RetryCommand = "command"
+)
+
+type (
+	// Retry is a retry policy of operations failing temporarily, like
+	// uploads. It is configured in the `retry` block of publish modules.
+	Retry struct {
+		// Attempts is the maximum number of attempts, including the
+		// first one. 1 disables retries.
+		Attempts int `yaml:"attempts"`
+		// Backoff is the delay before the first retry. It is doubled
+		// after each failed retry.
+		Backoff time.Duration `yaml:"backoff"`
+		// MaxBackoff limits the delay between attempts. Delays requested
+		// by the server (eg. by Retry-After headers) are always honored.
+		MaxBackoff time.Duration `yaml:"max_backoff"`
+		// On lists retry classes of errors to be retried
+		On []string `yaml:"on"`
+	}
+
+	// RetryableError is an error, which can be retried if its class is
+	// selected by the Retry policy. After is the delay the server asked
+	// for, if any.
+	RetryableError struct {
+		Class string
+		After time.Duration
+		Err   error
+	}
+)
+
+// DefaultRetry returns the default retry policy of publish modules. It
+// retries transient errors only: network, server, and rate limit classes.
+func DefaultRetry() *Retry {
+	return &Retry{
+		Attempts:   3,
+		Backoff:    2 * time.Second,
+		MaxBackoff: time.Minute,
+		On:         []string{RetryNetwork, RetryServer, RetryRateLimit},
+	}
+}
+
+// RetryClasses returns all retry classes
+func RetryClasses() []string {
+	return []string{RetryNetwork, RetryServer, RetryRateLimit, RetryCommand}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks retry settings. Problems are reported as FieldErrors of
+// field.
+func (retry *Retry) Validate(field string) error {
+	var errs Errors
+
+	if retry == nil {
+		return nil
+	}
+
+	if retry.Attempts < 1 {
+		errs.Add(NewFieldError(field, "attempts must be at least 1"))
+	}
+
+	if retry.Backoff < 0 || retry.MaxBackoff < 0 {
+		errs.Add(NewFieldError(field, "backoff must not be negative"))
+	}
+
+	for _, class := range retry.On {
+		if !contains(RetryClasses(), class) {
+			errs.Add(NewFieldError(field, "unknown retry class %q", class))
+		}
+	}
+
+	return errs.Err()
+}
+
+// Do runs fn until it succeeds, it returns an error not selected for
+// retrying, or it runs out of attempts. It waits between attempts with
+// exponential backoff, or as long as the server asked for. It stops
+// waiting when cx is done. Retries are logged with the operation's name.
+// A nil Retry runs fn only once.
+func (retry *Retry) Do(cx context.Context, name string, fn func() error) error {
+	if retry == nil {
+		return fn()
+	}
+
+	backoff := retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		class, after := retryClass(err)
+		if cx.Err() != nil || class == "" || !contains(retry.On, class) {
+			return err
+		}
+
+		if attempt >= retry.Attempts {
+			if attempt > 1 {
+				return fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+
+			return err
+		}
+
+		delay := backoff
+		if retry.MaxBackoff > 0 && delay > retry.MaxBackoff {
+			delay = retry.MaxBackoff
+		}
+
+		if after > delay {
+			delay = after
+		}
+
+		log.Printf("      %s failed (%s), retrying in %s: %v", name, class, delay, err)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-cx.Done():
+			timer.Stop()
+
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// retryClass returns the retry class of an error, and the delay the
+// server asked for. Errors without a class are not retried.
+func retryClass(err error) (string, time.Duration) {
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return retryable.Class, retryable.After
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return "", 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return RetryCommand, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return RetryNetwork, 0
+	}
+
+	return "", 0
+}
+
+func contains(list []string, item string) bool {
+	for _, listItem := range list {
+		if listItem == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package modules_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+)
+
+func TestRetry_Do(t *testing.T) {
+	serverErr := &modules.RetryableError{Class: modules.RetryServer, Err: errors.New("bad gateway")}
+
+	tests := []struct {
+		name     string
+		on       []string
+		failures []error
+		wantRuns int
+		errStr   string
+	}{
+		{
+			name:     "success",
+			on:       modules.RetryClasses(),
+			wantRuns: 1,
+		},
+		{
+			name:     "recovers",
+			on:       modules.RetryClasses(),
+			failures: []error{serverErr, serverErr},
+			wantRuns: 3,
+		},
+		{
+			name:     "gives up",
+			on:       modules.RetryClasses(),
+			failures: []error{serverErr, serverErr, serverErr},
+			wantRuns: 3,
+			errStr:   "bad gateway (gave up after 3 attempts)",
+		},
+		{
+			name:     "class not selected",
+			on:       []string{modules.RetryRateLimit},
+			failures: []error{serverErr},
+			wantRuns: 1,
+			errStr:   "bad gateway",
+		},
+		{
+			name:     "not retryable",
+			on:       modules.RetryClasses(),
+			failures: []error{errors.New("not found")},
+			wantRuns: 1,
+			errStr:   "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			retry := &modules.Retry{Attempts: 3, Backoff: time.Millisecond, On: tt.on}
+			runs := 0
+
+			err := retry.Do(context.Background(), "test", func() error {
+				runs++
+
+				if runs <= len(tt.failures) {
+					return tt.failures[runs-1]
+				}
+
+				return nil
+			})
+
+			if (err != nil) != (tt.errStr != "") || err != nil && err.Error() != tt.errStr {
+				t.Errorf("Retry.Do() error = %v, want %q", err, tt.errStr)
+			}
+
+			if runs != tt.wantRuns {
+				t.Errorf("Retry.Do() ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestRetry_Validate(t *testing.T) {
+	retry := &modules.Retry{Attempts: 0, Backoff: -time.Second, On: []string{"network", "weather"}}
+
+	err := retry.Validate("retry")
+	if err == nil {
+		t.Fatal("Retry.Validate() expected error")
+	}
+
+	want := []string{
+		"retry: attempts must be at least 1",
+		"retry: backoff must not be negative",
+		`retry: unknown retry class "weather"`,
+	}
+
+	if got := err.Error(); got != strings.Join(want, "\n") {
+		t.Errorf("Retry.Validate() error = %q, want %q", got, want)
+	}
+}
+
+func TestDefaultRetry(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	if exitErr == nil {
+		t.Fatal("command unexpectedly succeeded")
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantRuns int
+	}{
+		{name: "network", err: &net.DNSError{Err: "timeout", IsTimeout: true}, wantRuns: 3},
+		{name: "server", err: &modules.RetryableError{Class: modules.RetryServer, Err: errors.New("bad gateway")}, wantRuns: 3},
+		{name: "rate limit", err: &modules.RetryableError{Class: modules.RetryRateLimit, Err: errors.New("slow down")}, wantRuns: 3},
+		{name: "command", err: exitErr, wantRuns: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			retry := modules.DefaultRetry()
+			retry.Backoff = time.Millisecond
+			runs := 0
+
+			_ = retry.Do(context.Background(), "test", func() error {
+				runs++
+
+				return tt.err
+			})
+
+			if runs != tt.wantRuns {
+				t.Errorf("Retry.Do() ran %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package modules
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/julian7/goshipdone/ctx"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Selector is a YAML representation of an artifact selector, used by
+	// `builds` fields of modules. It is decoded from a list of artifact
+	// IDs, and artifact filters (mappings of id, os, arch, goarm, osarch,
+	// kind, and filename), from a single ID, or filter, or from a mapping
+	// of `include`, and `exclude` lists of the same format. IDs, OS-arch
+	// names, and file names are glob patterns.
+	//
+	// Eg.
+	//
+	// ```yaml
+	// builds:
+	//   include: [{kind: archive, os: linux}]
+	//   exclude: [{arch: "arm*"}]
+	// ```
+	Selector struct {
+		ctx.Selector
+	}
+
+	// Filters is a YAML representation of a list of artifact filters. It
+	// is a list of OS-arch names, like linux-386, and artifact filters, in
+	// the same format as Selector's.
+	Filters []*ctx.ArtifactFilter
+
+	// Skip is a list of filters of artifacts to be skipped, used by
+	// `skip` fields of modules.
+	Skip = Filters
+)
+
+// nolint: gochecknoglobals
+var filterKeys = map[string]bool{
+	"id":       true,
+	"os":       true,
+	"arch":     true,
+	"goarm":    true,
+	"osarch":   true,
+	"kind":     true,
+	"filename": true,
+}
+
+// SelectIDs returns a selector of artifacts by their IDs
+func SelectIDs(ids ...string) Selector {
+	return Selector{Selector: *ctx.IDSelector(ids...)}
+}
+
+// UnmarshalYAML decodes a selector from its list, or mapping forms
+func (sel *Selector) UnmarshalYAML(node *yaml.Node) error {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	*sel = Selector{}
+
+	if node.Kind != yaml.MappingNode || !hasKey(node, "include", "exclude") {
+		filters, err := decodeFilters(node, idFilter)
+		if err != nil {
+			return err
+		}
+
+		sel.Include = filters
+
+		return nil
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+
+		filters, err := decodeFilters(node.Content[idx+1], idFilter)
+		if err != nil {
+			return err
+		}
+
+		switch key.Value {
+		case "include":
+			sel.Include = filters
+		case "exclude":
+			sel.Exclude = filters
+		default:
+			return NewConfigError(key, fmt.Errorf("unknown selector field %q, expecting include, or exclude", key.Value))
+		}
+	}
+
+	return nil
+}
+
+// MarshalYAML returns the selector in its simplest form
+func (sel Selector) MarshalYAML() (interface{}, error) {
+	include := encodeFilters(sel.Include, idFilter)
+
+	if len(sel.Exclude) == 0 {
+		return include, nil
+	}
+
+	return map[string]interface{}{
+		"include": include,
+		"exclude": encodeFilters(sel.Exclude, idFilter),
+	}, nil
+}
+
+// RequireSelector returns a FieldError if the selector selects nothing
+func RequireSelector(field string, sel Selector) error {
+	if len(sel.Include) == 0 {
+		return NewFieldError(field, "must not be empty")
+	}
+
+	return nil
+}
+
+// Validate checks patterns of the selector. Problems are reported as
+// FieldErrors of field.
+func (sel *Selector) Validate(field string) error {
+	var errs Errors
+
+	errs.Extend(validateFilters(field, sel.Include))
+	errs.Extend(validateFilters(field, sel.Exclude))
+
+	return errs.Err()
+}
+
+// Skipping returns the selector, excluding artifacts to be skipped too
+func (sel *Selector) Skipping(skip Skip) *ctx.Selector {
+	return sel.Selector.Without(skip...)
+}
+
+// UnmarshalYAML decodes OS-arch names, and artifact filters
+func (filters *Filters) UnmarshalYAML(node *yaml.Node) error {
+	decoded, err := decodeFilters(node, osArchFilter)
+	if err != nil {
+		return err
+	}
+
+	*filters = decoded
+
+	return nil
+}
+
+// MarshalYAML returns filters, with OS-arch names in plain string format
+func (filters Filters) MarshalYAML() (interface{}, error) {
+	return encodeFilters(filters, osArchFilter), nil
+}
+
+// Validate checks filters. Problems are reported as FieldErrors of field.
+func (filters Filters) Validate(field string) error {
+	return validateFilters(field, filters)
+}
+
+// Match checks whether an artifact matches any of the filters
+func (filters Filters) Match(art *ctx.Artifact) bool {
+	for _, filter := range filters {
+		if filter.Match(art) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterForm converts between artifact filters, and their plain string
+// forms. It returns an empty string for filters without a plain form.
+type filterForm struct {
+	parse  func(string) *ctx.ArtifactFilter
+	format func(*ctx.ArtifactFilter) string
+}
+
+// nolint: gochecknoglobals
+var (
+	idFilter = filterForm{
+		parse: func(id string) *ctx.ArtifactFilter { return &ctx.ArtifactFilter{ID: id} },
+		format: func(filter *ctx.ArtifactFilter) string {
+			if filter.IsIDOnly() {
+				return filter.ID
+			}
+
+			return ""
+		},
+	}
+	osArchFilter = filterForm{
+		parse: func(osarch string) *ctx.ArtifactFilter { return &ctx.ArtifactFilter{OsArch: osarch} },
+		format: func(filter *ctx.ArtifactFilter) string {
+			if filter.OsArch != "" && *filter == (ctx.ArtifactFilter{OsArch: filter.OsArch}) {
+				return filter.OsArch
+			}
+
+			return ""
+		},
+	}
+)
+
+// decodeFilters decodes a list of filters, or a single filter, where
+// plain strings are parsed by form
+func decodeFilters(node *yaml.Node, form filterForm) ([]*ctx.ArtifactFilter, error) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	items := []*yaml.Node{node}
+
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+
+	filters := make([]*ctx.ArtifactFilter, 0, len(items))
+
+	for _, item := range items {
+		for item.Kind == yaml.AliasNode {
+			item = item.Alias
+		}
+
+		switch item.Kind {
+		case yaml.ScalarNode:
+			filters = append(filters, form.parse(item.Value))
+		case yaml.MappingNode:
+			filter, err := decodeFilter(item)
+			if err != nil {
+				return nil, err
+			}
+
+			filters = append(filters, filter)
+		default:
+			return nil, NewConfigError(item, fmt.Errorf("artifact filter is `%v`, not a string or a mapping", item.Tag))
+		}
+	}
+
+	return filters, nil
+}
+
+func decodeFilter(node *yaml.Node) (*ctx.ArtifactFilter, error) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		if !filterKeys[key.Value] {
+			return nil, NewConfigError(key, fmt.Errorf("unknown artifact filter field %q", key.Value))
+		}
+	}
+
+	filter := &ctx.ArtifactFilter{}
+
+	if err := node.Decode(filter); err != nil {
+		return nil, NewConfigError(node, fmt.Errorf("artifact filter cannot be decoded: %w", err))
+	}
+
+	return filter, nil
+}
+
+func encodeFilters(filters []*ctx.ArtifactFilter, form filterForm) []interface{} {
+	items := make([]interface{}, 0, len(filters))
+
+	for _, filter := range filters {
+		if plain := form.format(filter); plain != "" {
+			items = append(items, plain)
+
+			continue
+		}
+
+		items = append(items, filter)
+	}
+
+	return items
+}
+
+func validateFilters(field string, filters []*ctx.ArtifactFilter) error {
+	var errs Errors
+
+	for _, filter := range filters {
+		for _, pattern := range filter.Patterns() {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs.Add(NewFieldError(field, "invalid pattern %q", pattern))
+			}
+		}
+
+		if filter.ArmVersion != 0 && (filter.ArmVersion < 5 || filter.ArmVersion > 7) {
+			errs.Add(NewFieldError(field, "invalid ARM version %d, valid values are 5, 6, and 7", filter.ArmVersion))
+		}
+	}
+
+	return errs.Err()
+}
+
+func hasKey(node *yaml.Node, keys ...string) bool {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		for _, key := range keys {
+			if node.Content[idx].Value == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
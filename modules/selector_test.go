@@ -0,0 +1,126 @@
+package modules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSelector_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    ctx.Selector
+		errStr  string
+	}{
+		{
+			name:    "ID list",
+			content: `[default, "arch*"]`,
+			want:    *ctx.IDSelector("default", "arch*"),
+		},
+		{
+			name:    "single ID",
+			content: `default`,
+			want:    *ctx.IDSelector("default"),
+		},
+		{
+			name:    "single filter",
+			content: `{kind: archive, os: linux}`,
+			want:    ctx.Selector{Include: []*ctx.ArtifactFilter{{Kind: ctx.KindArchive, OS: "linux"}}},
+		},
+		{
+			name: "include and exclude",
+			content: `
+include: [default, {kind: checksum}]
+exclude: [{os: windows, goarm: 7}]
+`,
+			want: ctx.Selector{
+				Include: []*ctx.ArtifactFilter{{ID: "default"}, {Kind: ctx.KindChecksum}},
+				Exclude: []*ctx.ArtifactFilter{{OS: "windows", ArmVersion: 7}},
+			},
+		},
+		{
+			name:    "unknown filter field",
+			content: `[{id: default, platform: linux}]`,
+			errStr:  `line 1, column 16: unknown artifact filter field "platform"`,
+		},
+		{
+			name:    "unknown selector field",
+			content: "include: [default]\nonly: [archive]",
+			errStr:  `line 2, column 1: unknown selector field "only", expecting include, or exclude`,
+		},
+		{
+			name:    "unknown kind",
+			content: `[{kind: tarball}]`,
+			errStr:  `unknown artifact kind "tarball"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var sel modules.Selector
+
+			err := yaml.Unmarshal([]byte(tt.content), &sel)
+			if tt.errStr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errStr) {
+					t.Errorf("Selector.UnmarshalYAML() error = %v, want %q", err, tt.errStr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Selector.UnmarshalYAML() unexpected error: %v", err)
+			}
+
+			if diff := deep.Equal(sel.Selector, tt.want); diff != nil {
+				t.Errorf("Selector.UnmarshalYAML() %v", diff)
+			}
+		})
+	}
+}
+
+func TestSkip_UnmarshalYAML(t *testing.T) {
+	var skip modules.Skip
+
+	if err := yaml.Unmarshal([]byte(`[linux-386, {os: windows, kind: archive}]`), &skip); err != nil {
+		t.Fatalf("Skip.UnmarshalYAML() unexpected error: %v", err)
+	}
+
+	want := modules.Skip{{OsArch: "linux-386"}, {OS: "windows", Kind: ctx.KindArchive}}
+	if diff := deep.Equal(skip, want); diff != nil {
+		t.Errorf("Skip.UnmarshalYAML() %v", diff)
+	}
+
+	content, err := yaml.Marshal(skip)
+	if err != nil {
+		t.Fatalf("Skip.MarshalYAML() unexpected error: %v", err)
+	}
+
+	if got, want := string(content), "- linux-386\n- os: windows\n  kind: archive\n"; got != want {
+		t.Errorf("Skip.MarshalYAML() = %q, want %q", got, want)
+	}
+}
+
+func TestSelector_Validate(t *testing.T) {
+	sel := modules.Selector{Selector: ctx.Selector{
+		Include: []*ctx.ArtifactFilter{{ID: "[default"}},
+		Exclude: []*ctx.ArtifactFilter{{ArmVersion: 8}},
+	}}
+
+	err := sel.Validate("builds")
+	want := "builds: invalid pattern \"[default\"\nbuilds: invalid ARM version 8, valid values are 5, 6, and 7"
+
+	if err == nil || err.Error() != want {
+		t.Errorf("Selector.Validate() error = %v, want %q", err, want)
+	}
+
+	if err := modules.RequireSelector("builds", modules.Selector{}); err == nil {
+		t.Errorf("RequireSelector() accepted an empty selector")
+	}
+}
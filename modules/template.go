@@ -20,12 +20,16 @@ type TemplateData struct {
 	ArchiveName string
 	// Env is a copy of environment variables set in ctx.Context
 	Env *withenv.Env
+	// Filename is the file name of the artifact being processed
+	Filename string
 	// Git is a copy of git-related info from ctx.Context
 	Git *ctx.GitData
 	// OSArch defines target operating system and architecture
 	OSArch *ctx.OsArch
 	// ProjectName defines local filename of the resource
 	ProjectName string
+	// Publish is true if publish stage is enabled
+	Publish bool
 	// Version defines artifact's version
 	Version string
 	// Ext contains executable extension
@@ -42,26 +46,14 @@ func NewTemplate(cx context.Context) (*TemplateData, error) {
 		Env:         context.Env,
 		Git:         context.Git,
 		ProjectName: context.ProjectName,
+		Publish:     context.Publish,
 		Version:     context.Version,
 	}, nil
 }
 
 // Parse parses a string based on TemplateData, and returns output in string format
 func (td *TemplateData) Parse(name, text string) (string, error) {
-	tmpl := template.New(name).Funcs(template.FuncMap{
-		"Arch":     func() string { return td.OSArch.Arch },
-		"ArchName": func() string { return td.OSArch.ArchName() },
-		"OS":       func() string { return td.OSArch.OS },
-		"OSExt": func() string {
-			if td.OSArch.OS == "windows" {
-				return ".exe"
-			}
-
-			return ""
-		},
-	})
-	_, err := tmpl.Parse(text)
-
+	tmpl, err := td.template(name, text)
 	if err != nil {
 		return "", err
 	}
@@ -73,3 +65,18 @@ func (td *TemplateData) Parse(name, text string) (string, error) {
 
 	return td.Env.Expand(out.String()), nil
 }
+
+func (td *TemplateData) template(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"Arch":     func() string { return td.OSArch.Arch },
+		"ArchName": func() string { return td.OSArch.ArchName() },
+		"OS":       func() string { return td.OSArch.OS },
+		"OSExt": func() string {
+			if td.OSArch.OS == "windows" {
+				return ".exe"
+			}
+
+			return ""
+		},
+	}).Parse(text)
+}
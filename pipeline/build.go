@@ -1,20 +1,14 @@
 package pipeline
 
 import (
-	"context"
-
-	"github.com/julian7/goshipdone/ctx"
-	"github.com/julian7/goshipdone/internal/modules"
+	intmod "github.com/julian7/goshipdone/internal/modules"
+	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
-// LoadBuildPipeline creates a new BuildPipeline by reading YAML
-// contents of a byte slice. Then, it makes sure default modules
-// are loaded, providing safe defaults.
-func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
-	modules.Register()
-
-	pipeline := New([]*Stage{
+// buildStages returns the stages of a build pipeline
+func buildStages() []*Stage {
+	return []*Stage{
 		{
 			Name:   "setup",
 			Plural: "setups",
@@ -26,21 +20,86 @@ func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
 		{
 			Name:   "publish",
 			Plural: "publishes",
-			SkipFN: func(cx context.Context) bool {
-				context, err := ctx.GetShipContext(cx)
-				if err != nil {
-					return true
-				}
-				return !context.Publish
-			},
+			SkipFN: skipUnlessPublishing,
 		},
-	})
+	}
+}
+
+// BuildStageNames maps configuration keys of build pipeline stages to
+// their names, including user-defined stages in the `stages` section of
+// node. Invalid stage definitions are ignored.
+func BuildStageNames(node *yaml.Node) map[string]string {
+	names := map[string]string{}
+
+	for _, stg := range buildStages() {
+		names[stg.Plural] = stg.Name
+	}
+
+	if node == nil || node.Kind != yaml.MappingNode {
+		return names
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value != stagesKey {
+			continue
+		}
+
+		var defs []*StageDefinition
+
+		_ = node.Content[idx+1].Decode(&defs)
+
+		for _, def := range defs {
+			if def == nil || def.Name == "" {
+				continue
+			}
+
+			if def.Plural == "" {
+				def.Plural = def.Name + "s"
+			}
+
+			names[def.Plural] = def.Name
+		}
+	}
+
+	return names
+}
+
+// BuildSchema returns a JSON Schema of build pipeline configuration,
+// covering all registered modules.
+func BuildSchema() *Schema {
+	intmod.Register()
+
+	return New(buildStages()).Schema()
+}
+
+// LoadBuildPipeline creates a new BuildPipeline by reading YAML
+// contents of a byte slice. Then, it makes sure default modules
+// are loaded, providing safe defaults. It reports all configuration
+// problems at once: unknown stages, modules, and fields, as well as
+// problems reported by modules implementing modules.Validator.
+func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
+	var node yaml.Node
 
-	err := yaml.Unmarshal(ymlcontent, pipeline)
-	if err != nil {
+	if err := yaml.Unmarshal(ymlcontent, &node); err != nil {
 		return nil, err
 	}
 
+	return LoadBuildPipelineNode(&node)
+}
+
+// LoadBuildPipelineNode creates a new BuildPipeline from an already parsed
+// YAML node, the same way LoadBuildPipeline does.
+func LoadBuildPipelineNode(node *yaml.Node) (*Pipeline, error) {
+	intmod.Register()
+
+	pipeline := New(buildStages())
+
+	var errs modules.Errors
+
+	if !isEmpty(node) {
+		errs.Extend(node.Decode(pipeline))
+	}
+
 	for _, kind := range []string{
 		"setup:env",
 		"setup:project",
@@ -50,5 +109,24 @@ func LoadBuildPipeline(ymlcontent []byte) (*Pipeline, error) {
 		_ = pipeline.LoadDefault(kind)
 	}
 
+	errs.Extend(pipeline.Validate())
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := pipeline.Resolve(); err != nil {
+		return nil, err
+	}
+
 	return pipeline, nil
 }
+
+// isEmpty returns true if node contains no configuration at all
+func isEmpty(node *yaml.Node) bool {
+	if node.Kind == yaml.DocumentNode {
+		return len(node.Content) == 0 || isEmpty(node.Content[0])
+	}
+
+	return node.Kind == 0 || (node.Kind == yaml.ScalarNode && node.Tag == "!!null")
+}
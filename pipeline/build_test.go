@@ -167,6 +167,14 @@ func TestBuildPipeline_Run(t *testing.T) {
 		return stg
 	}
 
+	conditional := func(stg *pipeline.Stage, conditions ...string) *pipeline.Stage {
+		for idx, condition := range conditions {
+			stg.Modules[idx].If = condition
+		}
+
+		return stg
+	}
+
 	tests := []struct {
 		name       string
 		Pipeline   *pipeline.Pipeline
@@ -197,6 +205,18 @@ func TestBuildPipeline_Run(t *testing.T) {
 			wantReport: 3,
 			wantErr:    false,
 		},
+		{
+			name: "conditions",
+			Pipeline: &pipeline.Pipeline{
+				Stages: []*pipeline.Stage{
+					conditional(buildStage("setup", "setups", "success", "success"), "yes", "{{.Version}}"),
+					conditional(buildStage("build", "builds", "success", "failure"), "", "false"),
+					buildStage("publish", "publishes", "success"),
+				},
+			},
+			wantReport: 3,
+			wantErr:    false,
+		},
 		{
 			name: "has error",
 			Pipeline: &pipeline.Pipeline{
@@ -214,7 +234,7 @@ func TestBuildPipeline_Run(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			reportCounter = 0
-			err := tt.Pipeline.Run()
+			err := tt.Pipeline.Run(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("BuildPipeline.Run() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -0,0 +1,192 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// stagesKey is the configuration key of user-defined stages
+	stagesKey = "stages"
+	// extensionPrefix is the prefix of top-level keys ignored by the
+	// pipeline, like blocks of shared anchors
+	extensionPrefix = "x-"
+)
+
+// StageDefinition declares a user-defined stage in the `stages` section of
+// the configuration.
+type StageDefinition struct {
+	// Name is the name of the stage, used by module registrations
+	Name string `yaml:"name"`
+	// Plural is the configuration key of the stage's modules. Default:
+	// Name with an "s" suffix.
+	Plural string `yaml:"plural"`
+	// After is the name of the stage this stage runs after. Default: the
+	// last stage.
+	After string `yaml:"after"`
+	// SkipEnv is an environment variable, skipping the stage if set to a
+	// truthy value
+	SkipEnv string `yaml:"skip_env"`
+	// PublishOnly skips the stage, unless publishing is enabled
+	PublishOnly bool `yaml:"publish_only"`
+	// If is a condition in template format, skipping the stage if false
+	If string `yaml:"if"`
+}
+
+// Describe documents fields of StageDefinition
+func (*StageDefinition) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "User-defined stage",
+		Fields: map[string]string{
+			"name":         "Name of the stage",
+			"plural":       "Configuration key of the stage's modules (default: name + \"s\")",
+			"after":        "Name of the stage this stage runs after (default: the last stage)",
+			"skip_env":     "Environment variable skipping the stage, if set to a truthy value",
+			"publish_only": "Skip the stage, unless publishing is enabled",
+			"if":           "Condition in template format, skipping the stage if false",
+		},
+	}
+}
+
+// Stage returns a new stage from its definition
+func (def *StageDefinition) Stage() *Stage {
+	stg := NewStage(def.Name, def.Plural)
+	stg.If = def.If
+	stg.SkipEnv = def.SkipEnv
+
+	if def.PublishOnly {
+		stg.SkipFN = skipUnlessPublishing
+	}
+
+	return stg
+}
+
+// skipUnlessPublishing is a Stage.SkipFN skipping the stage if publishing
+// is not enabled
+func skipUnlessPublishing(cx context.Context) bool {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return true
+	}
+
+	return !context.Publish
+}
+
+// defineStages adds user-defined stages from the `stages` section of the
+// configuration.
+func (pip *Pipeline) defineStages(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return modules.NewConfigError(node, fmt.Errorf("definition of `%s` is not a sequence", stagesKey))
+	}
+
+	var errs modules.Errors
+
+	// defined contains stages placed after another one
+	defined := map[*Stage]*Stage{}
+
+	for idx, child := range node.Content {
+		def := &StageDefinition{}
+		name := fmt.Sprintf("stage definition #%d", idx+1)
+
+		if err := checkFields(name, child, def); err != nil {
+			errs.Extend(err)
+
+			continue
+		}
+
+		if err := child.Decode(def); err != nil {
+			errs.Add(positioned(child, fmt.Errorf("%s: %w", name, err)))
+
+			continue
+		}
+
+		if def.Plural == "" && def.Name != "" {
+			def.Plural = def.Name + "s"
+		}
+
+		if err := pip.checkDefinition(def); err != nil {
+			errs.Add(positionedProblems(child, name, err))
+
+			continue
+		}
+
+		stg := def.Stage()
+		pos := len(pip.Stages)
+
+		if def.After != "" {
+			pos = pip.stageIndex(def.After) + 1
+			after := pip.Stages[pos-1]
+			defined[stg] = after
+
+			for pos < len(pip.Stages) && placedAfter(defined, pip.Stages[pos], after) {
+				pos++
+			}
+		}
+
+		pip.Stages = append(pip.Stages[:pos], append([]*Stage{stg}, pip.Stages[pos:]...)...)
+	}
+
+	return errs.Err()
+}
+
+// checkDefinition checks a stage definition against existing stages
+func (pip *Pipeline) checkDefinition(def *StageDefinition) error {
+	var errs modules.Errors
+
+	errs.Add(modules.RequireField("name", def.Name))
+
+	for _, stg := range pip.Stages {
+		if def.Name != "" && (stg.Name == def.Name || stg.Plural == def.Name) {
+			errs.Add(modules.NewFieldError("name", "stage %q already exists", def.Name))
+		}
+
+		if def.Plural != "" && (stg.Name == def.Plural || stg.Plural == def.Plural) {
+			errs.Add(modules.NewFieldError("plural", "stage %q already exists", def.Plural))
+		}
+	}
+
+	if def.Plural == stagesKey {
+		errs.Add(modules.NewFieldError("plural", "%q is reserved", stagesKey))
+	}
+
+	if strings.HasPrefix(def.Plural, extensionPrefix) {
+		errs.Add(modules.NewFieldError("plural", "%q prefix is reserved", extensionPrefix))
+	}
+
+	if def.After != "" && pip.stageIndex(def.After) < 0 {
+		errs.Add(modules.NewFieldError("after", "unknown stage %q", def.After))
+	}
+
+	if err := modules.ParseCondition(def.If); err != nil {
+		errs.Add(&modules.FieldError{Field: "if", Err: err})
+	}
+
+	return errs.Err()
+}
+
+// placedAfter returns true if stg is defined to be after another stage,
+// directly or indirectly.
+func placedAfter(defined map[*Stage]*Stage, stg, after *Stage) bool {
+	for stg = defined[stg]; stg != nil; stg = defined[stg] {
+		if stg == after {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (pip *Pipeline) stageIndex(name string) int {
+	for idx, stg := range pip.Stages {
+		if stg.Name == name {
+			return idx
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,151 @@
+package pipeline_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+func TestLoadBuildPipeline_stages(t *testing.T) {
+	var signed int
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "sign",
+		Type:  "signer",
+		Factory: func() modules.Pluggable {
+			return &testModuleRegistration{reporter: func() { signed++ }}
+		},
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(`---
+setups:
+- type: project
+  target: ` + t.TempDir() + `
+builds:
+- type: show
+signs:
+- type: signer
+tests:
+- type: show
+announces:
+- type: show
+stages:
+- name: test
+  after: build
+- name: sign
+  after: build
+  skip_env: TEST_SKIP_SIGN
+- name: package
+  after: test
+  if: "{{.Publish}}"
+- name: announce
+  publish_only: true
+`))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() unexpected error: %v", err)
+	}
+
+	names := make([]string, 0, len(pip.Stages))
+	for _, stg := range pip.Stages {
+		names = append(names, stg.Name+"/"+stg.Plural)
+	}
+
+	want := "setup/setups build/builds test/tests package/packages sign/signs publish/publishes announce/announces"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("LoadBuildPipeline() stages = %s, want %s", got, want)
+	}
+
+	if mod := pip.StageByName("sign").Modules; len(mod) != 1 {
+		t.Errorf("LoadBuildPipeline() loaded %d modules into sign stage, want 1", len(mod))
+	}
+
+	plan, err := pip.Plan()
+	if err != nil {
+		t.Fatalf("Pipeline.Plan() unexpected error: %v", err)
+	}
+
+	skipped := map[string]bool{}
+	for _, stg := range plan.Stages {
+		skipped[stg.Name] = stg.Skipped
+	}
+
+	for name, want := range map[string]bool{"test": false, "package": true, "sign": false, "announce": true} {
+		if skipped[name] != want {
+			t.Errorf("Pipeline.Plan() stage %s skipped = %v, want %v", name, skipped[name], want)
+		}
+	}
+
+	os.Setenv("TEST_SKIP_SIGN", "true")
+	defer os.Unsetenv("TEST_SKIP_SIGN")
+
+	if err := pip.Run(context.Background()); err != nil {
+		t.Fatalf("Pipeline.Run() unexpected error: %v", err)
+	}
+
+	if signed != 0 {
+		t.Errorf("Pipeline.Run() ran sign stage %d times, despite TEST_SKIP_SIGN", signed)
+	}
+}
+
+func TestLoadBuildPipeline_stageErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		ymlcontent string
+		errStr     string
+	}{
+		{
+			name:       "not a sequence",
+			ymlcontent: "---\nstages: test\n",
+			errStr:     "line 2, column 9: definition of `stages` is not a sequence",
+		},
+		{
+			name:       "unknown field",
+			ymlcontent: "---\nstages:\n- name: test\n  before: build\n",
+			errStr:     `line 4, column 3: stage definition #1: unknown field "before"`,
+		},
+		{
+			name:       "existing stage",
+			ymlcontent: "---\nstages:\n- name: build\n  plural: tests\n- name: test\n  plural: setups\n",
+			errStr: `line 3, column 3: stage definition #1: name: stage "build" already exists` + "\n" +
+				`line 6, column 3: stage definition #2: plural: stage "setups" already exists`,
+		},
+		{
+			name:       "reserved plural",
+			ymlcontent: "---\nstages:\n- name: test\n  plural: x-tests\n",
+			errStr:     `line 4, column 3: stage definition #1: plural: "x-" prefix is reserved`,
+		},
+		{
+			name:       "unknown after",
+			ymlcontent: "---\nstages:\n- name: test\n  after: check\n",
+			errStr:     `line 4, column 3: stage definition #1: after: unknown stage "check"`,
+		},
+		{
+			name:       "missing name",
+			ymlcontent: "---\nstages:\n- after: build\n",
+			errStr:     "line 3, column 3: stage definition #1: name: required",
+		},
+		{
+			name:       "modules of undefined stage",
+			ymlcontent: "---\ntests:\n- type: show\n",
+			errStr:     `line 2, column 1: unknown stage "tests"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.LoadBuildPipeline([]byte(tt.ymlcontent))
+			if err == nil {
+				t.Fatalf("LoadBuildPipeline() expected error %q", tt.errStr)
+			}
+
+			if err.Error() != tt.errStr {
+				t.Errorf("LoadBuildPipeline() error = %q, want %q", err.Error(), tt.errStr)
+			}
+		})
+	}
+}
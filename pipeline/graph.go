@@ -0,0 +1,285 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// dependencies describes artifact IDs a module reads and writes. IDs can
+// be glob patterns, matching all artifact IDs they cover, but produced by
+// modules defined earlier only. Modules implementing neither
+// modules.Consumer nor modules.Producer are barriers: they run after all
+// modules defined before them, and before all modules defined after them.
+type dependencies struct {
+	barrier  bool
+	consumes []string
+	produces []string
+}
+
+func newDependencies(mod *modules.Module) *dependencies {
+	deps := &dependencies{}
+
+	consumer, isConsumer := mod.Pluggable.(modules.Consumer)
+	if isConsumer {
+		deps.consumes = consumer.Consumes()
+	}
+
+	producer, isProducer := mod.Pluggable.(modules.Producer)
+	if isProducer {
+		deps.produces = producer.Produces()
+	}
+
+	deps.barrier = !isConsumer && !isProducer
+
+	return deps
+}
+
+func (deps *dependencies) hasProduct(id string) bool {
+	for _, product := range deps.produces {
+		if product == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchIDs checks whether two artifact IDs, or ID patterns can refer to
+// the same artifacts
+func matchIDs(left, right string) bool {
+	return ctx.MatchID(left, right) || ctx.MatchID(right, left)
+}
+
+// producersOf returns indices of producers of an artifact ID, consumed
+// by the module at idx. Glob patterns refer to modules defined earlier
+// only, as they would refer to later consumers of the same artifacts too.
+func producersOf(producers map[string][]int, id string, idx int) []int {
+	pattern := strings.ContainsAny(id, "*?[")
+	items := []int{}
+
+	for key, values := range producers {
+		if !matchIDs(key, id) {
+			continue
+		}
+
+		for _, producer := range values {
+			if producer != idx && (!pattern || producer < idx) {
+				items = append(items, producer)
+			}
+		}
+	}
+
+	return items
+}
+
+// graph returns the dependencies of each module in the stage, as
+// module indices. A module consuming an artifact ID depends on all other
+// modules producing it. Modules producing the same ID (eg. modifying it
+// in place) run in their definition order.
+func (stg *Stage) graph() ([][]int, error) {
+	deps := make([]*dependencies, len(stg.Modules))
+	producers := map[string][]int{}
+
+	for idx, mod := range stg.Modules {
+		deps[idx] = newDependencies(mod)
+
+		for _, id := range deps[idx].produces {
+			producers[id] = append(producers[id], idx)
+		}
+	}
+
+	edges := make([][]int, len(stg.Modules))
+
+	for idx, dep := range deps {
+		requires := map[int]bool{}
+
+		for prev := 0; prev < idx; prev++ {
+			if dep.barrier || deps[prev].barrier {
+				requires[prev] = true
+			}
+		}
+
+		for _, id := range dep.consumes {
+			modifier := dep.hasProduct(id)
+
+			for _, producer := range producersOf(producers, id, idx) {
+				if modifier && producer > idx {
+					continue
+				}
+
+				requires[producer] = true
+			}
+		}
+
+		for _, id := range dep.produces {
+			for _, producer := range producers[id] {
+				if producer < idx {
+					requires[producer] = true
+				}
+			}
+		}
+
+		for prev := range deps {
+			if requires[prev] {
+				edges[idx] = append(edges[idx], prev)
+			}
+		}
+	}
+
+	if cycle := findCycle(edges); cycle != nil {
+		names := make([]string, 0, len(cycle))
+		for _, idx := range cycle {
+			names = append(names, stg.moduleName(idx))
+		}
+
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(names, " -> "))
+	}
+
+	return edges, nil
+}
+
+// order returns module indices in an order satisfying all dependencies,
+// keeping definition order wherever possible.
+func (stg *Stage) order() ([]int, error) {
+	edges, err := stg.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]int, 0, len(edges))
+	added := make([]bool, len(edges))
+
+	for len(ordered) < len(edges) {
+		for idx := range edges {
+			if added[idx] {
+				continue
+			}
+
+			ready := true
+
+			for _, dep := range edges[idx] {
+				if !added[dep] {
+					ready = false
+
+					break
+				}
+			}
+
+			if ready {
+				ordered = append(ordered, idx)
+				added[idx] = true
+
+				break
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+// unresolved reports artifact IDs consumed by modules of the stage, which
+// are neither produced by previous stages (provided in `known`), nor by
+// other modules of this stage. Artifact IDs produced by the stage are
+// added to `known`.
+func (stg *Stage) unresolved(known map[string]bool) error {
+	deps := make([]*dependencies, len(stg.Modules))
+	producers := map[string][]int{}
+
+	for idx, mod := range stg.Modules {
+		deps[idx] = newDependencies(mod)
+
+		for _, id := range deps[idx].produces {
+			producers[id] = append(producers[id], idx)
+		}
+	}
+
+	var errs modules.Errors
+
+	for idx, dep := range deps {
+		for _, id := range dep.consumes {
+			if len(producersOf(producers, id, idx)) == 0 && !isKnown(known, id) {
+				errs.Add(fmt.Errorf(
+					"stage %s: %s consumes unknown artifact ID %q",
+					stg.Name,
+					stg.moduleName(idx),
+					id,
+				))
+			}
+		}
+	}
+
+	for id := range producers {
+		known[id] = true
+	}
+
+	return errs.Err()
+}
+
+// isKnown checks whether an artifact ID pattern matches any known IDs
+func isKnown(known map[string]bool, id string) bool {
+	for key := range known {
+		if matchIDs(key, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (stg *Stage) moduleName(idx int) string {
+	return fmt.Sprintf("%s #%d (%s)", stg.Plural, idx+1, stg.Modules[idx].Type)
+}
+
+// findCycle returns module indices forming a dependency cycle, or nil if
+// the graph is acyclic.
+func findCycle(edges [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(edges))
+	stack := []int{}
+
+	var visit func(int) []int
+
+	visit = func(idx int) []int {
+		state[idx] = visiting
+		stack = append(stack, idx)
+
+		for _, dep := range edges[idx] {
+			switch state[dep] {
+			case visiting:
+				for pos, item := range stack {
+					if item == dep {
+						cycle := append([]int{}, stack[pos:]...)
+						return append(cycle, dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[idx] = visited
+
+		return nil
+	}
+
+	for idx := range edges {
+		if state[idx] == unvisited {
+			if cycle := visit(idx); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testDependentModule struct {
+	Inputs  []string
+	Outputs []string
+	Name    string
+	run     func(name string)
+}
+
+func (mod *testDependentModule) Consumes() []string {
+	return mod.Inputs
+}
+
+func (mod *testDependentModule) Produces() []string {
+	return mod.Outputs
+}
+
+func (mod *testDependentModule) Run(context.Context) error {
+	if mod.run != nil {
+		mod.run(mod.Name)
+	}
+
+	return nil
+}
+
+func registerDependentModule(run func(string)) {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage: "*",
+		Type:  "dependent",
+		Factory: func() modules.Pluggable {
+			return &testDependentModule{run: run}
+		},
+	})
+}
+
+func TestLoadBuildPipeline_dependencies(t *testing.T) {
+	registerDependentModule(nil)
+
+	tests := []struct {
+		name       string
+		ymlcontent string
+		errStrs    []string
+	}{
+		{
+			name: "resolved",
+			ymlcontent: `---
+builds:
+- type: dependent
+  outputs: [a]
+- type: dependent
+  inputs: [a]
+  outputs: [b]
+publishes:
+- type: dependent
+  inputs: [b]
+`,
+		},
+		{
+			name: "consumer before producer",
+			ymlcontent: `---
+builds:
+- type: dependent
+  inputs: [a]
+- type: dependent
+  outputs: [a]
+`,
+		},
+		{
+			name: "unknown reference",
+			ymlcontent: `---
+builds:
+- type: dependent
+  outputs: [a]
+- type: dependent
+  inputs: [a, c]
+`,
+			errStrs: []string{`stage build: builds #2 (dependent) consumes unknown artifact ID "c"`},
+		},
+		{
+			name: "glob reference",
+			ymlcontent: `---
+builds:
+- type: dependent
+  outputs: [linux-amd64]
+- type: dependent
+  inputs: ["linux-*"]
+publishes:
+- type: dependent
+  inputs: ["*"]
+- type: dependent
+  inputs: ["windows-*"]
+`,
+			errStrs: []string{`stage publish: publishes #2 (dependent) consumes unknown artifact ID "windows-*"`},
+		},
+		{
+			name: "globs refer to earlier producers",
+			ymlcontent: `---
+builds:
+- type: dependent
+  outputs: [a]
+- type: dependent
+  inputs: ["*"]
+  outputs: [b]
+- type: dependent
+  inputs: ["*"]
+  outputs: [c]
+`,
+		},
+		{
+			name: "modifier does not produce for itself",
+			ymlcontent: `---
+builds:
+- type: dependent
+  inputs: [a]
+  outputs: [a]
+`,
+			errStrs: []string{`consumes unknown artifact ID "a"`},
+		},
+		{
+			name: "cycle",
+			ymlcontent: `---
+builds:
+- type: dependent
+  inputs: [a]
+  outputs: [b]
+- type: dependent
+  inputs: [b]
+  outputs: [a]
+`,
+			errStrs: []string{"stage build: dependency cycle: builds #1 (dependent) -> builds #2 (dependent) -> builds #1 (dependent)"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.LoadBuildPipeline([]byte(tt.ymlcontent))
+			if len(tt.errStrs) == 0 {
+				if err != nil {
+					t.Errorf("LoadBuildPipeline() unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Errorf("LoadBuildPipeline() unexpected success")
+				return
+			}
+
+			for _, errStr := range tt.errStrs {
+				if !strings.Contains(err.Error(), errStr) {
+					t.Errorf("LoadBuildPipeline() error = %q, want %q", err, errStr)
+				}
+			}
+		})
+	}
+}
+
+func TestStage_RunConcurrently(t *testing.T) {
+	var (
+		order     []string
+		orderLock sync.Mutex
+		started   sync.WaitGroup
+	)
+
+	started.Add(2)
+
+	registerDependentModule(func(name string) {
+		if strings.HasPrefix(name, "build") {
+			started.Done()
+
+			waitCh := make(chan struct{})
+
+			go func() {
+				started.Wait()
+				close(waitCh)
+			}()
+
+			select {
+			case <-waitCh:
+			case <-time.After(time.Second):
+				t.Errorf("module %s hasn't been run concurrently", name)
+			}
+		}
+
+		orderLock.Lock()
+		order = append(order, name)
+		orderLock.Unlock()
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(`---
+builds:
+- type: dependent
+  name: archive
+  inputs: [a, b]
+- type: dependent
+  name: build-a
+  outputs: [a]
+- type: dependent
+  name: build-b
+  outputs: [b]
+`))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() unexpected error: %v", err)
+	}
+
+	if err := pip.StageByName("build").Run(context.Background()); err != nil {
+		t.Fatalf("Stage.Run() unexpected error: %v", err)
+	}
+
+	if len(order) != 3 || order[2] != "archive" {
+		t.Errorf("Stage.Run() run order = %v, want archive last", order)
+	}
+}
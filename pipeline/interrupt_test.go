@@ -0,0 +1,63 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testSleepModule struct {
+	ran bool
+}
+
+func (mod *testSleepModule) Run(cx context.Context) error {
+	mod.ran = true
+
+	return modules.RunCommand(cx, nil, "sleep", "10")
+}
+
+func TestPipeline_Run_timeout(t *testing.T) {
+	stg := pipeline.NewStage("build", "builds")
+	stg.Modules = []*modules.Module{
+		{Type: "sleep", Timeout: 50 * time.Millisecond, Pluggable: &testSleepModule{}},
+	}
+
+	start := time.Now()
+	err := pipeline.New([]*pipeline.Stage{stg}).Run(context.Background())
+
+	if err == nil || !strings.Contains(err.Error(), "stage build: sleep: timed out after 50ms") {
+		t.Errorf("Pipeline.Run() error = %v, want timeout", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Pipeline.Run() took %s, command was not killed", elapsed)
+	}
+}
+
+func TestPipeline_Run_interrupted(t *testing.T) {
+	cx, cancel := context.WithCancel(context.Background())
+	sleeping := &testSleepModule{}
+	next := &testSleepModule{}
+
+	build := pipeline.NewStage("build", "builds")
+	build.Modules = []*modules.Module{{Type: "sleep", Pluggable: sleeping}}
+
+	publish := pipeline.NewStage("publish", "publishes")
+	publish.Modules = []*modules.Module{{Type: "next", Pluggable: next}}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := pipeline.New([]*pipeline.Stage{build, publish}).Run(cx)
+
+	if err == nil || !strings.Contains(err.Error(), "stage build: sleep: interrupted: context canceled") {
+		t.Errorf("Pipeline.Run() error = %v, want interrupted", err)
+	}
+
+	if !sleeping.ran || next.ran {
+		t.Errorf("Pipeline.Run() ran interrupted module: %v, next module: %v", sleeping.ran, next.ran)
+	}
+}
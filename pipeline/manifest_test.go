@@ -0,0 +1,201 @@
+package pipeline_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testTargetModule struct {
+	dir string
+}
+
+func (mod *testTargetModule) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	context.TargetDir = mod.dir
+	context.ProjectName = "project"
+
+	return nil
+}
+
+type testFileModule struct {
+	location string
+}
+
+func (mod *testFileModule) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(mod.location, []byte("binary"), 0o600); err != nil {
+		return err
+	}
+
+	context.Artifacts.Add(&ctx.Artifact{ID: "default", Filename: "binary", Location: mod.location})
+
+	return nil
+}
+
+func TestPipeline_Run_manifest(t *testing.T) {
+	dir := t.TempDir()
+
+	setup := pipeline.NewStage("setup", "setups")
+	setup.Modules = []*modules.Module{{Type: "target", Pluggable: &testTargetModule{dir: dir}}}
+
+	build := pipeline.NewStage("build", "builds")
+	build.Modules = []*modules.Module{
+		{Type: "file", Pluggable: &testFileModule{location: path.Join(dir, "binary")}},
+		{Type: "skipped", If: "false", Pluggable: &testFileModule{}},
+	}
+
+	publish := pipeline.NewStage("publish", "publishes")
+	publish.Disabled = true
+
+	pip := pipeline.New([]*pipeline.Stage{setup, build, publish})
+	pip.Observers = modules.Observers{}
+
+	if err := pip.Run(context.Background()); err != nil {
+		t.Fatalf("Pipeline.Run() unexpected error: %v", err)
+	}
+
+	manifest, err := ctx.LoadManifest(path.Join(dir, ctx.ManifestFilename))
+	if err != nil {
+		t.Fatalf("ctx.LoadManifest() error = %v", err)
+	}
+
+	if manifest.ProjectName != "project" || len(manifest.Artifacts) != 1 || manifest.Artifacts[0].Size != 6 {
+		t.Errorf("Pipeline.Run() manifest %+v", manifest)
+	}
+
+	statuses := []string{}
+	for _, timing := range manifest.Timings {
+		statuses = append(statuses, timing.Stage+":"+timing.Module+":"+timing.Status)
+	}
+
+	want := []string{
+		"setup:target:done",
+		"setup::done",
+		"build:file:done",
+		"build:skipped:skipped",
+		"build::done",
+		"publish::skipped",
+	}
+
+	if diff := deep.Equal(statuses, want); diff != nil {
+		t.Errorf("Pipeline.Run() manifest timings %v", diff)
+	}
+}
+
+type testPublishModule struct {
+	version   string
+	artifacts int
+	publish   bool
+}
+
+func (mod *testPublishModule) Run(cx context.Context) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	mod.version = context.Version
+	mod.artifacts = len(context.Artifacts)
+	mod.publish = context.Publish
+
+	return nil
+}
+
+func TestPipeline_RunFromManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errStr  string
+	}{
+		{name: "verified", content: "binary"},
+		{
+			name:    "modified artifact",
+			content: "tampered",
+			errStr:  "verifying manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			location := path.Join(dir, "binary")
+
+			if err := os.WriteFile(location, []byte("binary"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := ctx.NewManifest(&ctx.Context{
+				Version:   "1.0.0",
+				Git:       &ctx.GitData{},
+				Artifacts: ctx.Artifacts{{ID: "default", Filename: "binary", Location: location}},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := manifest.Save(path.Join(dir, ctx.ManifestFilename)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.WriteFile(location, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			publisher := &testPublishModule{}
+
+			setup := pipeline.NewStage("setup", "setups")
+			setup.Modules = []*modules.Module{{Type: "target", Pluggable: &testTargetModule{dir: dir}}}
+
+			build := pipeline.NewStage("build", "builds")
+			build.Modules = []*modules.Module{{Type: "failing", Pluggable: &testObservedModule{fail: true}}}
+
+			publish := pipeline.NewStage("publish", "publishes")
+			publish.Modules = []*modules.Module{{Type: "publisher", Pluggable: publisher}}
+
+			pip := pipeline.New([]*pipeline.Stage{setup, build, publish})
+			pip.Observers = modules.Observers{}
+
+			if err := pip.StartAt("publish"); err != nil {
+				t.Fatal(err)
+			}
+
+			err = pip.RunFromManifest(context.Background(), "")
+			if tt.errStr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errStr) {
+					t.Errorf("Pipeline.RunFromManifest() error = %v, want %q", err, tt.errStr)
+				}
+
+				if publisher.version != "" {
+					t.Error("Pipeline.RunFromManifest() published unverified artifacts")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Pipeline.RunFromManifest() unexpected error: %v", err)
+			}
+
+			if publisher.version != "1.0.0" || publisher.artifacts != 1 || !publisher.publish {
+				t.Errorf("Pipeline.RunFromManifest() restored context %+v", publisher)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testObservedModule struct {
+	fail bool
+}
+
+func (mod *testObservedModule) Run(cx context.Context) error {
+	if mod.fail {
+		return errors.New("failed")
+	}
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	context.AddArtifact(&ctx.Artifact{ID: "observed", Filename: "observed"})
+
+	return nil
+}
+
+func TestPipeline_AddObserver(t *testing.T) {
+	var (
+		events []string
+		lock   sync.Mutex
+	)
+
+	stage := func(name string, mods ...*modules.Module) *pipeline.Stage {
+		stg := pipeline.NewStage(name, name+"s")
+		stg.Modules = mods
+
+		return stg
+	}
+
+	pip := pipeline.New([]*pipeline.Stage{
+		stage("setup"),
+		stage("build",
+			&modules.Module{Type: "observed", Pluggable: &testObservedModule{}},
+			&modules.Module{Type: "skipped", If: "false", Pluggable: &testObservedModule{}},
+		),
+		stage("publish", &modules.Module{Type: "failing", Pluggable: &testObservedModule{fail: true}}),
+	})
+	pip.Stages[0].Disabled = true
+
+	pip.AddObserver(modules.ObserverFunc(func(event *modules.Event) {
+		item := fmt.Sprintf("%s %s %s", event.Kind, event.Stage, event.Module)
+
+		switch event.Kind {
+		case modules.ArtifactAdded:
+			item = fmt.Sprintf("%s %s", event.Kind, event.Artifact.ID)
+		case modules.ModuleFailed, modules.StageFinished:
+			item = fmt.Sprintf("%s error=%v", item, event.Err != nil)
+		}
+
+		lock.Lock()
+		events = append(events, item)
+		lock.Unlock()
+	}))
+
+	if err := pip.Run(context.Background()); err == nil {
+		t.Errorf("Pipeline.Run() expected error")
+	}
+
+	want := []string{
+		"stage started setup ",
+		"stage skipped setup ",
+		"stage finished setup  error=false",
+		"stage started build ",
+		"module started build observed",
+		"artifact added observed",
+		"module finished build observed",
+		"module skipped build skipped",
+		"stage finished build  error=false",
+		"stage started publish ",
+		"module started publish failing",
+		"module failed publish failing error=true",
+		"stage finished publish  error=true",
+	}
+
+	if diff := deep.Equal(events, want); diff != nil {
+		t.Errorf("Pipeline.Run() events %v", diff)
+	}
+}
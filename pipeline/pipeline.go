@@ -6,15 +6,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path"
 	"strings"
 
 	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
 // Pipeline is a generic pipeline, with a registry and stages configured.
+// Events of a run are sent to Observers. If there are no observers set,
+// events are logged with modules.LogObserver. Publish enables publishing
+// after the first stage, regardless of its modules (eg. skip_publish).
 type Pipeline struct {
-	Stages []*Stage
+	Observers modules.Observers
+	Publish   bool
+	Stages    []*Stage
 }
 
 func New(stages []*Stage) *Pipeline {
@@ -24,17 +32,32 @@ func New(stages []*Stage) *Pipeline {
 	return pip
 }
 
-// UnmarshalYAML parses YAML node to load its modules
+// UnmarshalYAML parses YAML node to load its modules. User-defined
+// stages in the `stages` section are added first, regardless of their
+// position in the document. Keys with the `x-` prefix are ignored, they
+// can hold anchors shared by modules.
 func (pip *Pipeline) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return errors.New("pipeline definition is not a map")
 	}
 
+	var errs modules.Errors
+
 	l := len(node.Content)
+
+	for i := 0; i < l; i += 2 {
+		if node.Content[i].Value == stagesKey {
+			errs.Extend(pip.defineStages(node.Content[i+1]))
+		}
+	}
+
 	for i := 0; i < l; i += 2 {
 		var stage *Stage
 
 		stageDefName := node.Content[i].Value
+		if stageDefName == stagesKey || strings.HasPrefix(stageDefName, extensionPrefix) {
+			continue
+		}
 
 		for _, st := range pip.Stages {
 			if st.Plural == stageDefName {
@@ -43,15 +66,30 @@ func (pip *Pipeline) UnmarshalYAML(node *yaml.Node) error {
 		}
 
 		if stage == nil {
+			errs.Add(modules.NewConfigError(
+				node.Content[i],
+				fmt.Errorf("unknown stage %q", stageDefName),
+			))
+
 			continue
 		}
 
-		if err := node.Content[i+1].Decode(stage); err != nil {
-			return fmt.Errorf("decoding %s stage: %w", stage.Name, err)
-		}
+		errs.Extend(node.Content[i+1].Decode(stage))
 	}
 
-	return nil
+	return errs.Err()
+}
+
+// Validate checks configuration of all modules implementing
+// modules.Validator, and reports all their problems at once.
+func (pip *Pipeline) Validate() error {
+	var errs modules.Errors
+
+	for _, stg := range pip.Stages {
+		errs.Extend(stg.Validate())
+	}
+
+	return errs.Err()
 }
 
 // LoadDefault loads a module into a stage, if not loaded yet
@@ -70,6 +108,92 @@ func (pip *Pipeline) LoadDefault(kind string) error {
 	return nil
 }
 
+// Resolve checks module dependencies in all stages. It reports dependency
+// cycles, and references to artifact IDs no module produces, either in
+// the same stage, or in any of the previous stages.
+func (pip *Pipeline) Resolve() error {
+	var errs modules.Errors
+
+	known := map[string]bool{}
+
+	for _, stg := range pip.Stages {
+		if _, err := stg.graph(); err != nil {
+			errs.Add(fmt.Errorf("stage %s: %w", stg.Name, err))
+		}
+
+		errs.Add(stg.unresolved(known))
+	}
+
+	return errs.Err()
+}
+
+// Only disables all stages, except the ones listed. The first stage is
+// never disabled, as all other stages rely on it.
+func (pip *Pipeline) Only(names ...string) error {
+	for _, name := range names {
+		if pip.StageByName(name) == nil {
+			return fmt.Errorf("unknown stage %q", name)
+		}
+	}
+
+	for idx, stg := range pip.Stages {
+		if idx == 0 {
+			continue
+		}
+
+		stg.Disabled = !isAllowed(stg.Name, names)
+	}
+
+	return nil
+}
+
+// StartAt disables all stages before the named one, except the first
+// stage, as all other stages rely on it.
+func (pip *Pipeline) StartAt(name string) error {
+	start := -1
+
+	for idx, stg := range pip.Stages {
+		if stg.Name == name {
+			start = idx
+		}
+	}
+
+	if start < 0 {
+		return fmt.Errorf("unknown stage %q", name)
+	}
+
+	for idx := 1; idx < start; idx++ {
+		pip.Stages[idx].Disabled = true
+	}
+
+	return nil
+}
+
+// RemoveModules removes modules from the pipeline by their kinds. A kind
+// is either in `stage:type` format, removing modules from a single stage,
+// or just a module type, removing modules from all stages.
+func (pip *Pipeline) RemoveModules(kinds ...string) error {
+	for _, kind := range kinds {
+		removed := 0
+		items := strings.SplitN(kind, ":", 2)
+
+		for _, stg := range pip.Stages {
+			switch {
+			case len(items) == 1:
+				removed += stg.RemoveModules(items[0])
+			case stg.Name == items[0]:
+				removed += stg.RemoveModules(items[1])
+			}
+		}
+
+		if removed == 0 {
+			return fmt.Errorf("no modules found of kind %q", kind)
+		}
+	}
+
+	return nil
+}
+
 func (pip *Pipeline) StageByName(name string) *Stage {
 	for _, stage := range pip.Stages {
 		if stage.Name == name {
@@ -81,15 +205,147 @@ func (pip *Pipeline) StageByName(name string) *Stage {
 }
 
 // Run executes build pipeline, calling Run on all
-// Modules
-func (pip *Pipeline) Run() error {
-	ctx := ctx.New(context.Background())
+// Modules. The run's manifest, with all artifacts created, is saved into
+// the target directory after each stage, even if the pipeline fails.
+// Cancelling cx stops the run: running modules are interrupted, and no
+// further modules are started.
+func (pip *Pipeline) Run(cx context.Context) error {
+	return pip.run(cx, nil)
+}
 
-	for _, stg := range pip.Stages {
-		if err := stg.Run(ctx); err != nil {
+// RunFromManifest executes the pipeline, starting from a manifest saved by
+// an earlier run. After the first stage, the context is restored from the
+// manifest (project name, version, git information, and artifacts), and
+// publishing is enabled. Artifacts are verified against their checksums
+// before any other stage starts. Filename defaults to the manifest in the
+// target directory. The manifest is not modified. Use StartAt to skip
+// stages creating artifacts.
+func (pip *Pipeline) RunFromManifest(cx context.Context, filename string) error {
+	return pip.run(cx, func(context *ctx.Context) error {
+		if filename == "" {
+			filename = path.Join(context.TargetDir, ctx.ManifestFilename)
+		}
+
+		manifest, err := ctx.LoadManifest(filename)
+		if err != nil {
 			return err
 		}
+
+		if err := manifest.Verify(); err != nil {
+			return fmt.Errorf("verifying manifest %s: %w", filename, err)
+		}
+
+		manifest.Restore(context)
+		context.Publish = true
+
+		return nil
+	})
+}
+
+// run executes the pipeline. If restore is set, it is called after the
+// first stage, instead of saving the run into the target directory.
+func (pip *Pipeline) run(cx context.Context, restore func(*ctx.Context) error) error {
+	recorder := &timings{}
+	observer := modules.Observers{pip.observer(), recorder}
+	cx = modules.WithObserver(ctx.New(cx), observer)
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	context.OnArtifact = func(artifact *ctx.Artifact) {
+		observer.Notify(&modules.Event{Kind: modules.ArtifactAdded, Artifact: artifact})
+	}
+
+	for idx, stg := range pip.Stages {
+		if err = stg.Run(cx); err != nil {
+			break
+		}
+
+		if idx == 0 && pip.Publish {
+			context.Publish = true
+		}
+
+		switch {
+		case restore != nil && idx == 0:
+			err = restore(context)
+		case restore == nil:
+			err = saveRun(context, recorder.list())
+		}
+
+		if err != nil {
+			break
+		}
 	}
 
-	return nil
+	if err != nil && restore == nil {
+		_ = saveRun(context, recorder.list())
+	}
+
+	return err
+}
+
+// AddObserver registers an observer, receiving events of pipeline runs.
+// The first observer added is registered besides the default logging.
+func (pip *Pipeline) AddObserver(observer modules.Observer) {
+	if pip.Observers == nil {
+		pip.Observers = modules.Observers{modules.LogObserver{}}
+	}
+
+	pip.Observers = append(pip.Observers, observer)
+}
+
+func (pip *Pipeline) observer() modules.Observer {
+	if pip.Observers == nil {
+		return modules.LogObserver{}
+	}
+
+	return pip.Observers
+}
+
+// saveRun writes the manifest of the run into the target directory. It
+// does nothing if the target directory is not known.
+func saveRun(context *ctx.Context, timings []*ctx.Timing) error {
+	if context.TargetDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(context.TargetDir, 0o755); err != nil { // nolint: gosec
+		return fmt.Errorf("creating target directory: %w", err)
+	}
+
+	manifest, err := ctx.NewManifest(context, timings)
+	if err != nil {
+		return fmt.Errorf("creating manifest: %w", err)
+	}
+
+	return manifest.Save(path.Join(context.TargetDir, ctx.ManifestFilename))
+}
+
+// Plan describes what Run would do, without running any modules. It asks
+// each module to describe its actions, and predict its artifacts.
+func (pip *Pipeline) Plan() (*modules.Plan, error) {
+	cx := ctx.New(context.Background())
+	plan := &modules.Plan{}
+
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, stg := range pip.Stages {
+		stagePlan, err := stg.Plan(cx)
+		if err != nil {
+			return nil, err
+		}
+
+		if idx == 0 && pip.Publish {
+			context.Publish = true
+		}
+
+		plan.Stages = append(plan.Stages, stagePlan)
+	}
+
+	return plan, nil
 }
@@ -0,0 +1,118 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+func testSelectionPipeline() *pipeline.Pipeline {
+	stage := func(name, plural string, types ...string) *pipeline.Stage {
+		stg := pipeline.NewStage(name, plural)
+
+		for _, typ := range types {
+			stg.Modules = append(stg.Modules, &modules.Module{Type: typ})
+		}
+
+		return stg
+	}
+
+	return pipeline.New([]*pipeline.Stage{
+		stage("setup", "setups", "env", "git"),
+		stage("build", "builds", "go", "tar", "show"),
+		stage("publish", "publishes", "artifact", "show"),
+	})
+}
+
+func TestPipeline_Only(t *testing.T) {
+	tests := []struct {
+		name         string
+		only         []string
+		wantDisabled []bool
+		wantErr      bool
+	}{
+		{"build", []string{"build"}, []bool{false, false, true}, false},
+		{"publish", []string{"publish"}, []bool{false, true, false}, false},
+		{"setup keeps only setup", []string{"setup"}, []bool{false, true, true}, false},
+		{"unknown", []string{"build", "nope"}, []bool{false, false, false}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pip := testSelectionPipeline()
+
+			err := pip.Only(tt.only...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Pipeline.Only() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for idx, stg := range pip.Stages {
+				if stg.Disabled != tt.wantDisabled[idx] {
+					t.Errorf("stage %s disabled = %v, want %v", stg.Name, stg.Disabled, tt.wantDisabled[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_StartAt(t *testing.T) {
+	tests := []struct {
+		name         string
+		start        string
+		wantDisabled []bool
+		wantErr      bool
+	}{
+		{"publish", "publish", []bool{false, true, false}, false},
+		{"build", "build", []bool{false, false, false}, false},
+		{"unknown", "nope", []bool{false, false, false}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pip := testSelectionPipeline()
+
+			err := pip.StartAt(tt.start)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Pipeline.StartAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for idx, stg := range pip.Stages {
+				if stg.Disabled != tt.wantDisabled[idx] {
+					t.Errorf("stage %s disabled = %v, want %v", stg.Name, stg.Disabled, tt.wantDisabled[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_RemoveModules(t *testing.T) {
+	tests := []struct {
+		name      string
+		kinds     []string
+		wantCount []int
+		wantErr   bool
+	}{
+		{"by kind", []string{"build:show"}, []int{2, 2, 2}, false},
+		{"by type", []string{"show"}, []int{2, 2, 1}, false},
+		{"multiple", []string{"git", "build:tar"}, []int{1, 2, 2}, false},
+		{"not found", []string{"publish:go"}, []int{2, 3, 2}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pip := testSelectionPipeline()
+
+			err := pip.RemoveModules(tt.kinds...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Pipeline.RemoveModules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for idx, stg := range pip.Stages {
+				if len(stg.Modules) != tt.wantCount[idx] {
+					t.Errorf("stage %s has %d modules, want %d", stg.Name, len(stg.Modules), tt.wantCount[idx])
+				}
+			}
+		})
+	}
+}
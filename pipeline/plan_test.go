@@ -0,0 +1,121 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testPlannerModule struct {
+	ID     string
+	Inputs []string
+	found  int
+}
+
+func (mod *testPlannerModule) Consumes() []string {
+	return mod.Inputs
+}
+
+func (mod *testPlannerModule) Produces() []string {
+	return []string{mod.ID}
+}
+
+func (mod *testPlannerModule) Run(context.Context) error {
+	return nil
+}
+
+func (mod *testPlannerModule) Plan(cx context.Context, plan *modules.ModulePlan) error {
+	context, err := ctx.GetShipContext(cx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range mod.Inputs {
+		mod.found += len(*context.Artifacts.ByID(id))
+	}
+
+	plan.AddCommand("touch", "dist/"+mod.ID, "with space")
+
+	return plan.AddArtifact(cx, &ctx.Artifact{ID: mod.ID, Filename: mod.ID, Location: "dist/" + mod.ID})
+}
+
+func TestPipeline_Plan(t *testing.T) {
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "build",
+		Type:    "planner",
+		Factory: func() modules.Pluggable { return &testPlannerModule{} },
+	})
+
+	pip, err := pipeline.LoadBuildPipeline([]byte(`---
+builds:
+- type: planner
+  id: second
+  inputs: [first]
+- type: planner
+  id: first
+- type: planner
+  id: third
+  if: "{{.Publish}}"
+- type: test
+  if: "{{not .Publish}}"
+`))
+	if err != nil {
+		t.Fatalf("LoadBuildPipeline() unexpected error: %v", err)
+	}
+
+	plan, err := pip.Plan()
+	if err != nil {
+		t.Fatalf("Pipeline.Plan() unexpected error: %v", err)
+	}
+
+	second := pip.StageByName("build").Modules[0].Pluggable.(*testPlannerModule)
+	if second.found != 1 {
+		t.Errorf("Pipeline.Plan() predicted %d artifacts for later modules, want 1", second.found)
+	}
+
+	var out bytes.Buffer
+	if err := plan.Write(&out, "text"); err != nil {
+		t.Fatalf("Plan.Write() unexpected error: %v", err)
+	}
+
+	want := `setup:
+  - env
+  - project
+  - git
+  - skip_publish
+build:
+  - planner
+      run: touch dist/first "with space"
+      artifact: first: first (noarch)
+  - planner
+      run: touch dist/second "with space"
+      artifact: second: second (noarch)
+  - planner (skipped: condition "{{.Publish}}" evaluated to "false")
+  - test (no plan available)
+publish: skipped
+`
+	if out.String() != want {
+		t.Errorf("Plan.Write() = %q, want %q", out.String(), want)
+	}
+
+	pip.Publish = true
+
+	if plan, err = pip.Plan(); err != nil {
+		t.Fatalf("Pipeline.Plan() unexpected error: %v", err)
+	}
+
+	out.Reset()
+
+	if err := plan.Write(&out, "text"); err != nil {
+		t.Fatalf("Plan.Write() unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "artifact: third: third (noarch)") || strings.Contains(out.String(), "publish: skipped") {
+		t.Errorf("Plan.Write() with publishing = %q", out.String())
+	}
+}
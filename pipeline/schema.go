@@ -0,0 +1,331 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// nolint: gochecknoglobals
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Schema is a JSON Schema document, or a part of it
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// Write writes the schema in indented JSON format
+func (schema *Schema) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(schema)
+}
+
+// Schema returns a JSON Schema of the pipeline's configuration, with
+// definitions of all registered modules. Each stage accepts modules
+// registered for the stage, and modules registered for all stages ("*"),
+// unless a stage-specific module of the same type exists. Other keys are
+// modules of user-defined stages, accepting modules registered for all
+// stages, and for stages not in the pipeline.
+func (pip *Pipeline) Schema() *Schema {
+	root := &Schema{
+		Schema:     schemaDraft,
+		Title:      "GoShipDone configuration",
+		Type:       "object",
+		Properties: map[string]*Schema{stagesKey: stageDefinitionsSchema()},
+		PatternProperties: map[string]*Schema{
+			"^" + extensionPrefix: {Description: "ignored, eg. for shared anchors"},
+		},
+		Definitions: map[string]*Schema{},
+	}
+
+	registrations := modules.Registrations()
+	known := map[string]bool{"*": true}
+
+	for _, registration := range registrations {
+		root.Definitions[registration.Kind()] = moduleSchema(registration)
+	}
+
+	for _, stg := range pip.Stages {
+		known[stg.Name] = true
+
+		root.Properties[stg.Plural] = &Schema{
+			Description: fmt.Sprintf("modules of %s stage", stg.Name),
+			Type:        "array",
+			Items:       moduleRefs(registrations, stg.Name),
+		}
+	}
+
+	userStages := []string{}
+
+	for _, registration := range registrations {
+		if !known[registration.Stage] {
+			known[registration.Stage] = true
+			userStages = append(userStages, registration.Stage)
+		}
+	}
+
+	root.AdditionalProperties = &Schema{
+		Description: "modules of a user-defined stage",
+		Type:        "array",
+		Items:       moduleRefs(registrations, userStages...),
+	}
+
+	return root
+}
+
+// moduleRefs returns references to definitions of modules registered for
+// all stages ("*"), and for the stages listed. Modules of later stages
+// take precedence over modules of the same type.
+func moduleRefs(registrations []*modules.ModuleRegistration, stages ...string) *Schema {
+	types := map[string]*modules.ModuleRegistration{}
+
+	for _, stage := range append([]string{"*"}, stages...) {
+		for _, registration := range registrations {
+			if registration.Stage == stage {
+				types[registration.Type] = registration
+			}
+		}
+	}
+
+	items := &Schema{}
+
+	for _, typ := range sortedKeys(types) {
+		items.OneOf = append(items.OneOf, &Schema{Ref: "#/definitions/" + types[typ].Kind()})
+	}
+
+	return items
+}
+
+func stageDefinitionsSchema() *Schema {
+	def := &StageDefinition{}
+	schema := valueSchema(reflect.ValueOf(def))
+	description := def.Describe()
+
+	schema.Description = description.Summary
+	schema.Required = []string{"name"}
+
+	for key, text := range description.Fields {
+		schema.Properties[key].Description = text
+	}
+
+	return &Schema{
+		Description: "user-defined stages",
+		Type:        "array",
+		Items:       schema,
+	}
+}
+
+func moduleSchema(registration *modules.ModuleRegistration) *Schema {
+	mod := registration.Factory()
+	value := reflect.ValueOf(mod)
+
+	description := &modules.Description{}
+	if describer, ok := mod.(modules.Describer); ok {
+		description = describer.Describe()
+	}
+
+	schema := valueSchema(value)
+	if schema.Type != "object" {
+		schema = &Schema{Type: "object", Properties: map[string]*Schema{}}
+	}
+
+	schema.Title = registration.Kind()
+	schema.Description = description.Summary
+	schema.Properties["type"] = &Schema{Const: registration.Type}
+	schema.Required = []string{"type"}
+
+	for _, key := range commonKeys {
+		if _, ok := schema.Properties[key]; !ok {
+			schema.Properties[key] = &Schema{Type: "string", Description: commonKeyDescriptions[key]}
+		}
+	}
+
+	for key, text := range description.Fields {
+		if prop, ok := schema.Properties[key]; ok {
+			prop.Description = text
+		}
+	}
+
+	return schema
+}
+
+// valueSchema returns the schema of a value, the way yaml.v3 decodes it,
+// including its current contents as default.
+func valueSchema(value reflect.Value) *Schema {
+	typ := value.Type()
+
+	for typ.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value = reflect.New(typ.Elem())
+		}
+
+		value = value.Elem()
+		typ = value.Type()
+	}
+
+	if schema := customSchema(value); schema != nil {
+		return schema
+	}
+
+	if typ == durationType {
+		return &Schema{Type: "string", Default: defaultValue(value)}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		return structSchema(value)
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:    "array",
+			Items:   valueSchema(reflect.New(typ.Elem()).Elem()),
+			Default: defaultValue(value),
+		}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: valueSchema(reflect.New(typ.Elem()).Elem()),
+			Default:              defaultValue(value),
+		}
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Default: defaultValue(value)}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Default: defaultValue(value)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Default: defaultValue(value)}
+	case reflect.String:
+		return &Schema{Type: "string", Default: defaultValue(value)}
+	}
+
+	return &Schema{}
+}
+
+// customSchema returns schema of types decoding themselves, or nil for
+// other types.
+func customSchema(value reflect.Value) *Schema {
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+
+	if !ptr.Type().Implements(unmarshalerType) {
+		return nil
+	}
+
+	schema := &Schema{Default: defaultValue(value)}
+
+	if enumerator, ok := ptr.Interface().(modules.Enumerator); ok {
+		schema.Type = "string"
+		schema.Enum = enumerator.Enum()
+	}
+
+	if mapping, ok := ptr.Interface().(modules.MappingForm); ok {
+		object := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+
+		for _, key := range mapping.MappingKeys() {
+			object.Properties[key] = &Schema{}
+		}
+
+		schema.OneOf = []*Schema{{Type: schema.Type, Enum: schema.Enum}, object}
+		schema.Type = ""
+		schema.Enum = nil
+	}
+
+	return schema
+}
+
+func structSchema(value reflect.Value) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: false,
+	}
+
+	typ := value.Type()
+
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		items := strings.Split(field.Tag.Get("yaml"), ",")
+
+		if items[0] == "-" {
+			continue
+		}
+
+		if isAllowed("inline", items[1:]) {
+			inlined := valueSchema(value.Field(idx))
+			for key, prop := range inlined.Properties {
+				schema.Properties[key] = prop
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := items[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		schema.Properties[name] = valueSchema(value.Field(idx))
+	}
+
+	return schema
+}
+
+// defaultValue returns the value in a YAML-compatible form, or nil for
+// zero values.
+func defaultValue(value reflect.Value) interface{} {
+	if value.IsZero() {
+		return nil
+	}
+
+	content, err := yaml.Marshal(value.Interface())
+	if err != nil {
+		return nil
+	}
+
+	var ret interface{}
+	if err := yaml.Unmarshal(content, &ret); err != nil {
+		return nil
+	}
+
+	return ret
+}
+
+func sortedKeys(items map[string]*modules.ModuleRegistration) []string {
+	keys := make([]string, 0, len(items))
+
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,125 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/goshipdone/modules"
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+type testSchemaModule struct {
+	Name     string
+	Count    int32
+	Disabled bool     `yaml:"is_disabled"`
+	Items    []string `yaml:"items"`
+	Ignored  string   `yaml:"-"`
+	hidden   string
+}
+
+func (mod *testSchemaModule) Run(context.Context) error {
+	return nil
+}
+
+func (mod *testSchemaModule) Describe() *modules.Description {
+	return &modules.Description{
+		Summary: "schema test",
+		Fields:  map[string]string{"name": "name of the test"},
+	}
+}
+
+func TestBuildSchema(t *testing.T) {
+	for _, stage := range []string{"*", "publish"} {
+		stage := stage
+		modules.RegisterModule(&modules.ModuleRegistration{
+			Stage: stage,
+			Type:  "schematest",
+			Factory: func() modules.Pluggable {
+				return &testSchemaModule{Name: stage, Items: []string{"a"}, hidden: "x"}
+			},
+		})
+	}
+
+	modules.RegisterModule(&modules.ModuleRegistration{
+		Stage:   "lint",
+		Type:    "schemastage",
+		Factory: func() modules.Pluggable { return &testSchemaModule{} },
+	})
+
+	schema := pipeline.BuildSchema()
+
+	refs := map[string]bool{}
+
+	for _, plural := range []string{"setups", "builds", "publishes"} {
+		for _, item := range schema.Properties[plural].Items.OneOf {
+			refs[plural+" "+item.Ref] = true
+		}
+	}
+
+	for _, ref := range []string{
+		"setups #/definitions/*:schematest",
+		"builds #/definitions/*:schematest",
+		"publishes #/definitions/publish:schematest",
+		"builds #/definitions/build:go",
+	} {
+		if !refs[ref] {
+			t.Errorf("BuildSchema() has no reference %s", ref)
+		}
+	}
+
+	if refs["builds #/definitions/lint:schemastage"] {
+		t.Errorf("BuildSchema() refers to lint:schemastage in builds")
+	}
+
+	if _, ok := schema.Definitions["lint:schemastage"]; !ok {
+		t.Errorf("BuildSchema() has no definition of lint:schemastage")
+	}
+
+	userStages := map[string]bool{}
+
+	for _, item := range schema.AdditionalProperties.(*pipeline.Schema).Items.OneOf {
+		userStages[item.Ref] = true
+	}
+
+	for _, ref := range []string{"#/definitions/*:schematest", "#/definitions/lint:schemastage"} {
+		if !userStages[ref] {
+			t.Errorf("BuildSchema() has no reference %s in user-defined stages", ref)
+		}
+	}
+
+	if refs["publishes #/definitions/*:schematest"] {
+		t.Errorf("BuildSchema() refers to *:schematest in publishes, despite a publish:schematest module")
+	}
+
+	want := &pipeline.Schema{
+		Title:       "*:schematest",
+		Description: "schema test",
+		Type:        "object",
+		Properties: map[string]*pipeline.Schema{
+			"name":        {Type: "string", Default: "*", Description: "name of the test"},
+			"count":       {Type: "integer"},
+			"is_disabled": {Type: "boolean"},
+			"items": {
+				Type:    "array",
+				Items:   &pipeline.Schema{Type: "string"},
+				Default: []interface{}{"a"},
+			},
+			"type": {Const: "schematest"},
+			"if": {
+				Type:        "string",
+				Description: "condition in template format, skipping the module if false",
+			},
+			"timeout": {
+				Type:        "string",
+				Description: "time limit of running the module, in Go duration format (eg. 5m)",
+			},
+		},
+		Required:             []string{"type"},
+		AdditionalProperties: false,
+	}
+
+	if diff := deep.Equal(schema.Definitions["*:schematest"], want); diff != nil {
+		t.Errorf("BuildSchema() %v", diff)
+	}
+}
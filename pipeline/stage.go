@@ -4,55 +4,81 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/julian7/goshipdone/ctx"
 	"github.com/julian7/goshipdone/modules"
 	"gopkg.in/yaml.v3"
 )
 
+// commonKeys are keys of module definitions handled by Stage, not by
+// modules themselves.
+// nolint: gochecknoglobals
+var commonKeys = []string{"type", "if", "timeout"}
+
+// commonKeyDescriptions documents commonKeys in the JSON Schema
+// nolint: gochecknoglobals
+var commonKeyDescriptions = map[string]string{
+	"if":      "condition in template format, skipping the module if false",
+	"timeout": "time limit of running the module, in Go duration format (eg. 5m)",
+}
+
 // Stage is a single stage in the pipeline
 type Stage struct {
-	loaded  map[string]bool
-	Modules []*modules.Module          `yaml:"-"`
-	Name    string                     `yaml:"-"`
-	Plural  string                     `yaml:"-"`
-	SkipFN  func(context.Context) bool `yaml:"-"`
+	loaded   map[string]bool
+	nodes    map[*modules.Module]*yaml.Node
+	Disabled bool                       `yaml:"-"`
+	If       string                     `yaml:"-"`
+	Modules  []*modules.Module          `yaml:"-"`
+	Name     string                     `yaml:"-"`
+	Plural   string                     `yaml:"-"`
+	SkipEnv  string                     `yaml:"-"`
+	SkipFN   func(context.Context) bool `yaml:"-"`
 }
 
 func NewStage(name, plural string) *Stage {
 	return &Stage{Name: name, Plural: plural}
 }
 
-// UnmarshalYAML parses YAML node to load its modules
+// UnmarshalYAML parses YAML node to load its modules. It reports
+// problems of all modules at once.
 func (stg *Stage) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.SequenceNode {
-		return fmt.Errorf("definition of `%s` is not a sequence", stg.Name)
+		return modules.NewConfigError(node, fmt.Errorf("definition of `%s` is not a sequence", stg.Name))
 	}
 
+	var errs modules.Errors
+
 	for idx, child := range node.Content {
 		if child.Kind != yaml.MappingNode {
-			return fmt.Errorf("item #%d of `%s` definition is not a map", idx+1, stg.Name)
+			errs.Add(modules.NewConfigError(
+				child,
+				fmt.Errorf("item #%d of `%s` definition is not a map", idx+1, stg.Name),
+			))
+
+			continue
 		}
 
 		itemType, err := getType(child)
 
 		if err != nil {
-			return fmt.Errorf(
+			errs.Add(modules.NewConfigError(child, fmt.Errorf(
 				"definition %s, item #%d: %w",
 				stg.Name,
 				idx+1,
 				err,
-			)
-		}
+			)))
 
-		if err := stg.Add(itemType, child, false); err != nil {
-			return err
+			continue
 		}
+
+		errs.Add(stg.Add(itemType, child, false))
 	}
 
-	return nil
+	return errs.Err()
 }
 
 // Add adds a single module into Stage, decoding a YAML node if provided.
@@ -81,7 +107,7 @@ func (stg *Stage) Add(itemType string, node *yaml.Node, once bool) error {
 	}
 
 	if !ok {
-		return fmt.Errorf("unknown module %s:%s", stg.Name, itemType)
+		return positioned(node, fmt.Errorf("unknown module %s:%s", stg.Name, itemType))
 	}
 
 	if once && stg.isLoaded(kind) {
@@ -89,43 +115,354 @@ func (stg *Stage) Add(itemType string, node *yaml.Node, once bool) error {
 	}
 
 	targetMod := targetModFactory()
+	module := &modules.Module{
+		Type:      itemType,
+		Pluggable: targetMod,
+	}
 
 	if node != nil {
-		if err := node.Decode(targetMod); err != nil {
-			return fmt.Errorf("cannot decode module %s: %w", kind, err)
+		if err := checkFields("module "+kind, node, targetMod, commonKeys...); err != nil {
+			return err
+		}
+
+		condition, err := getCondition(node)
+		if err != nil {
+			return positioned(node, fmt.Errorf("module %s: %w", kind, err))
+		}
+
+		module.If = condition
+
+		timeout, err := getTimeout(node)
+		if err != nil {
+			return positioned(node, fmt.Errorf("module %s: %w", kind, err))
+		}
+
+		module.Timeout = timeout
+
+		if err := stripCommonKeys(node).Decode(targetMod); err != nil {
+			return positioned(node, fmt.Errorf("module %s: %w", kind, err))
 		}
 	}
 
-	stg.Modules = append(stg.Modules, &modules.Module{
-		Type:      itemType,
-		Pluggable: targetMod,
-	})
+	stg.Modules = append(stg.Modules, module)
+
+	if node != nil {
+		if stg.nodes == nil {
+			stg.nodes = map[*modules.Module]*yaml.Node{}
+		}
+
+		stg.nodes[module] = node
+	}
 
 	stg.flagLoaded(kind)
 
 	return nil
 }
 
-// Run goes through all internally loaded modules, and run them
-// one by one.
+// Validate calls Validate on all modules implementing modules.Validator,
+// and reports all their problems, at their position in the YAML source
+// if known.
+func (stg *Stage) Validate() error {
+	var errs modules.Errors
+
+	for _, module := range stg.Modules {
+		validator, ok := module.Pluggable.(modules.Validator)
+		if !ok {
+			continue
+		}
+
+		err := validator.Validate()
+		if err == nil {
+			continue
+		}
+
+		errs.Extend(positionedProblems(
+			stg.nodes[module],
+			fmt.Sprintf("module %s:%s", stg.Name, module.Type),
+			err,
+		))
+	}
+
+	return errs.Err()
+}
+
+// positionedProblems prefixes all problems reported by a Validator with
+// name, and reports them at their position in node: problems of fields are
+// reported at their keys, and other problems at node itself.
+func positionedProblems(node *yaml.Node, name string, err error) error {
+	var errs modules.Errors
+
+	problems, ok := err.(modules.Errors)
+	if !ok {
+		problems = modules.Errors{err}
+	}
+
+	for _, problem := range problems {
+		problem = fmt.Errorf("%s: %w", name, problem)
+
+		var fieldErr *modules.FieldError
+		if errors.As(problem, &fieldErr) {
+			if key := findKey(node, fieldErr.Field); key != nil {
+				errs.Add(modules.NewConfigError(key, problem))
+
+				continue
+			}
+		}
+
+		errs.Add(positioned(node, problem))
+	}
+
+	return errs.Err()
+}
+
+// Run goes through all internally loaded modules, and run them. Modules
+// run concurrently, as soon as all the modules they depend on are done.
+// After a failure, no new modules are started, and all errors of
+// modules already running are reported.
 func (stg *Stage) Run(cx context.Context) error {
-	log.Printf("====> %s", strings.ToUpper(stg.Name))
+	observer := modules.ObserverFrom(cx)
+	observer.Notify(&modules.Event{Kind: modules.StageStarted, Stage: stg.Name})
+
+	start := time.Now()
+
+	err := stg.run(cx, observer)
+	if err != nil {
+		err = fmt.Errorf("stage %s: %w", stg.Name, err)
+	}
+
+	observer.Notify(&modules.Event{
+		Kind:     modules.StageFinished,
+		Stage:    stg.Name,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+
+	return err
+}
+
+func (stg *Stage) run(cx context.Context, observer modules.Observer) error {
+	reason, err := stg.skipped(cx)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		observer.Notify(&modules.Event{Kind: modules.StageSkipped, Stage: stg.Name, Reason: reason})
+
+		return nil
+	}
+
+	return stg.runModules(cx, observer)
+}
+
+// Plan describes what the stage would do, by asking all its modules to
+// plan their actions in dependency order.
+func (stg *Stage) Plan(cx context.Context) (*modules.StagePlan, error) {
+	plan := &modules.StagePlan{Name: stg.Name}
+
+	reason, err := stg.skipped(cx)
+	if err != nil {
+		return nil, fmt.Errorf("stage %s: %w", stg.Name, err)
+	}
+
+	if reason != "" {
+		plan.Skipped = true
+
+		return plan, nil
+	}
+
+	order, err := stg.order()
+	if err != nil {
+		return nil, fmt.Errorf("stage %s: %w", stg.Name, err)
+	}
+
+	for _, idx := range order {
+		module := stg.Modules[idx]
+		modPlan := &modules.ModulePlan{Type: module.Type}
+
+		enabled, reason, err := module.Enabled(cx)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", stg.Name, err)
+		}
+
+		if !enabled {
+			modPlan.Skipped = reason
+			plan.Modules = append(plan.Modules, modPlan)
+
+			continue
+		}
+
+		if err := module.Plan(cx, modPlan); err != nil {
+			return nil, fmt.Errorf("stage %s: %w", stg.Name, err)
+		}
+
+		plan.Modules = append(plan.Modules, modPlan)
+	}
+
+	return plan, nil
+}
+
+func (stg *Stage) runModules(cx context.Context, observer modules.Observer) error {
+	edges, err := stg.graph()
+	if err != nil {
+		return err
+	}
+
+	done := make([]chan struct{}, len(stg.Modules))
+	for idx := range done {
+		done[idx] = make(chan struct{})
+	}
+
+	errs := make([]error, len(stg.Modules))
+
+	var (
+		failed     bool
+		failedLock sync.RWMutex
+		wg         sync.WaitGroup
+	)
+
+	for idx := range stg.Modules {
+		wg.Add(1)
+
+		go func(idx int) {
+			defer wg.Done()
+			defer close(done[idx])
+
+			for _, dep := range edges[idx] {
+				<-done[dep]
+			}
+
+			failedLock.RLock()
+			skip := failed
+			failedLock.RUnlock()
+
+			if skip || cx.Err() != nil {
+				return
+			}
 
-	startMod := time.Now()
+			if err := stg.runModule(cx, observer, stg.Modules[idx]); err != nil {
+				errs[idx] = err
+
+				failedLock.Lock()
+				failed = true
+				failedLock.Unlock()
+			}
+		}(idx)
+	}
+
+	wg.Wait()
+
+	var runErrors modules.Errors
+
+	for _, err := range errs {
+		runErrors.Add(err)
+	}
+
+	if len(runErrors) == 0 {
+		return cx.Err()
+	}
+
+	return runErrors.Err()
+}
+
+// runModule runs a single module, if its condition allows, and reports
+// its events
+func (stg *Stage) runModule(cx context.Context, observer modules.Observer, module *modules.Module) error {
+	event := &modules.Event{Stage: stg.Name, Module: module.Type}
+
+	enabled, reason, err := module.Enabled(cx)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		event.Kind = modules.ModuleSkipped
+		event.Reason = reason
+		observer.Notify(event)
+
+		return nil
+	}
+
+	event.Kind = modules.ModuleStarted
+	observer.Notify(event)
+
+	start := time.Now()
+	err = module.Run(cx)
+
+	event = &modules.Event{
+		Kind:     modules.ModuleFinished,
+		Stage:    stg.Name,
+		Module:   module.Type,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+
+	if err != nil {
+		event.Kind = modules.ModuleFailed
+	}
+
+	observer.Notify(event)
+
+	return err
+}
+
+// skipped returns the reason if the stage should be skipped: it is
+// disabled, its SkipFN says so, its SkipEnv environment variable is set to
+// a truthy value, or its If condition is false.
+func (stg *Stage) skipped(cx context.Context) (string, error) {
+	if stg.Disabled {
+		return "disabled", nil
+	}
 
 	if stg.SkipFN != nil && stg.SkipFN(cx) {
-		log.Printf("SKIPPED")
-	} else {
-		for _, module := range stg.Modules {
-			if err := module.Run(cx); err != nil {
-				return fmt.Errorf("stage %s: %w", stg.Name, err)
+		return "skip rule", nil
+	}
+
+	if stg.SkipEnv != "" {
+		context, err := ctx.GetShipContext(cx)
+		if err != nil {
+			return "", err
+		}
+
+		if variable, ok := context.Env.Get(stg.SkipEnv); ok {
+			skip, err := strconv.ParseBool(variable)
+			if err != nil {
+				return "", fmt.Errorf("parsing %s as bool: %w", stg.SkipEnv, err)
+			}
+
+			if skip {
+				return fmt.Sprintf("%s is set", stg.SkipEnv), nil
 			}
 		}
 	}
 
-	log.Printf("<==== %s done in %s", strings.ToUpper(stg.Name), time.Since(startMod))
+	enabled, reason, err := modules.EvalCondition(cx, stg.If)
+	if err != nil || enabled {
+		return "", err
+	}
 
-	return nil
+	return reason, nil
+}
+
+// RemoveModules removes all modules of a type from the stage, and returns
+// the number of modules removed.
+func (stg *Stage) RemoveModules(itemType string) int {
+	kept := make([]*modules.Module, 0, len(stg.Modules))
+
+	for _, module := range stg.Modules {
+		if module.Type == itemType {
+			delete(stg.nodes, module)
+
+			continue
+		}
+
+		kept = append(kept, module)
+	}
+
+	removed := len(stg.Modules) - len(kept)
+	stg.Modules = kept
+
+	return removed
 }
 
 func (stg *Stage) isLoaded(kind string) bool {
@@ -146,6 +483,47 @@ func (stg *Stage) flagLoaded(kind string) {
 	stg.loaded[kind] = true
 }
 
+// positioned returns err as a modules.ConfigError at the position of node.
+// If err already wraps a modules.ConfigError, its position is kept, and
+// it is moved to the front of the message.
+func positioned(node *yaml.Node, err error) error {
+	var configErr *modules.ConfigError
+	if errors.As(err, &configErr) {
+		if configErr == err {
+			return err
+		}
+
+		message := strings.Replace(err.Error(), configErr.Error(), configErr.Err.Error(), 1)
+
+		return &modules.ConfigError{
+			Line:   configErr.Line,
+			Column: configErr.Column,
+			Err:    errors.New(message),
+		}
+	}
+
+	if node == nil {
+		return err
+	}
+
+	return modules.NewConfigError(node, err)
+}
+
+// findKey returns the key node of a mapping, or nil if not found
+func findKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx]
+		}
+	}
+
+	return nil
+}
+
 func getType(node *yaml.Node) (string, error) {
 	var itemType string
 
@@ -161,3 +539,74 @@ func getType(node *yaml.Node) (string, error) {
 
 	return "", errors.New("type not defined")
 }
+
+// getCondition returns the module's `if` condition, if defined, after
+// checking its syntax.
+func getCondition(node *yaml.Node) (string, error) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		val := node.Content[idx+1]
+
+		if key.Value != "if" {
+			continue
+		}
+
+		if val.Kind != yaml.ScalarNode {
+			return "", modules.NewConfigError(val, errors.New("if: must be a string"))
+		}
+
+		if err := modules.ParseCondition(val.Value); err != nil {
+			return "", modules.NewConfigError(val, fmt.Errorf("if: %w", err))
+		}
+
+		return val.Value, nil
+	}
+
+	return "", nil
+}
+
+// getTimeout returns the module's `timeout`, if defined
+func getTimeout(node *yaml.Node) (time.Duration, error) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		val := node.Content[idx+1]
+
+		if key.Value != "timeout" {
+			continue
+		}
+
+		if val.Kind != yaml.ScalarNode {
+			return 0, modules.NewConfigError(val, errors.New("timeout: must be a string"))
+		}
+
+		timeout, err := time.ParseDuration(val.Value)
+		if err != nil {
+			return 0, modules.NewConfigError(val, fmt.Errorf("timeout: %w", err))
+		}
+
+		if timeout <= 0 {
+			return 0, modules.NewConfigError(val, errors.New("timeout: must be positive"))
+		}
+
+		return timeout, nil
+	}
+
+	return 0, nil
+}
+
+// stripCommonKeys returns a copy of a mapping node without commonKeys,
+// to be decoded by the module itself.
+func stripCommonKeys(node *yaml.Node) *yaml.Node {
+	stripped := *node
+	stripped.Content = make([]*yaml.Node, 0, len(node.Content))
+
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if isAllowed(node.Content[idx].Value, commonKeys) {
+			continue
+		}
+
+		stripped.Content = append(stripped.Content, node.Content[idx], node.Content[idx+1])
+	}
+
+	return &stripped
+}
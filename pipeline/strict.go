@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/julian7/goshipdone/modules"
+	"gopkg.in/yaml.v3"
+)
+
+// nolint: gochecknoglobals
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkFields reports mapping keys of node, which are not decoded into
+// target, as yaml.v3 silently ignores them. It descends into nested
+// structures, unless they decode themselves. Keys listed in `allowed` are
+// accepted on the top level. Errors are prefixed with `name`.
+func checkFields(name string, node *yaml.Node, target interface{}, allowed ...string) error {
+	var errs modules.Errors
+
+	checkNode(&errs, node, reflect.TypeOf(target), name+": ", allowed)
+
+	return errs.Err()
+}
+
+func checkNode(errs *modules.Errors, node *yaml.Node, typ reflect.Type, prefix string, allowed []string) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if typ.Implements(unmarshalerType) || reflect.PtrTo(typ).Implements(unmarshalerType) {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		checkNode(errs, node, typ.Elem(), prefix, allowed)
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for idx, item := range node.Content {
+			checkNode(errs, item, typ.Elem(), fmt.Sprintf("%sitem #%d: ", prefix, idx+1), nil)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			checkNode(errs, node.Content[idx+1], typ.Elem(), prefix+node.Content[idx].Value+": ", nil)
+		}
+	case reflect.Struct:
+		if node.Kind == yaml.MappingNode {
+			checkMapping(errs, node, yamlFields(typ), prefix, allowed)
+		}
+	}
+}
+
+func checkMapping(
+	errs *modules.Errors,
+	node *yaml.Node,
+	fields map[string]reflect.Type,
+	prefix string,
+	allowed []string,
+) {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key := node.Content[idx]
+		val := node.Content[idx+1]
+
+		if key.Tag == "!!merge" {
+			for val.Kind == yaml.AliasNode {
+				val = val.Alias
+			}
+
+			merged := []*yaml.Node{val}
+			if val.Kind == yaml.SequenceNode {
+				merged = val.Content
+			}
+
+			for _, item := range merged {
+				for item.Kind == yaml.AliasNode {
+					item = item.Alias
+				}
+
+				checkMapping(errs, item, fields, prefix, allowed)
+			}
+
+			continue
+		}
+
+		if isAllowed(key.Value, allowed) {
+			continue
+		}
+
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			errs.Add(modules.NewConfigError(key, fmt.Errorf("%sunknown field %q", prefix, key.Value)))
+
+			continue
+		}
+
+		checkNode(errs, val, fieldType, prefix+key.Value+": ", nil)
+	}
+}
+
+func isAllowed(key string, allowed []string) bool {
+	for _, item := range allowed {
+		if key == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// yamlFields returns all YAML keys of a struct type, the way yaml.v3
+// decodes them.
+func yamlFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		items := strings.Split(tag, ",")
+		name := items[0]
+
+		if isAllowed("inline", items[1:]) {
+			inlined := field.Type
+			if inlined.Kind() == reflect.Ptr {
+				inlined = inlined.Elem()
+			}
+
+			if inlined.Kind() == reflect.Struct {
+				for key, val := range yamlFields(inlined) {
+					fields[key] = val
+				}
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"sync"
+
+	"github.com/julian7/goshipdone/ctx"
+	"github.com/julian7/goshipdone/modules"
+)
+
+// Timing statuses
+const (
+	statusDone    = "done"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// timings is an observer, collecting timings of stages and modules for
+// the run's manifest
+type timings struct {
+	lock    sync.Mutex
+	items   []*ctx.Timing
+	skipped map[string]bool
+}
+
+// Notify records finished, failed, and skipped stages and modules
+func (t *timings) Notify(event *modules.Event) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	timing := &ctx.Timing{Stage: event.Stage, Seconds: event.Duration.Seconds()}
+
+	switch event.Kind {
+	case modules.StageSkipped:
+		if t.skipped == nil {
+			t.skipped = map[string]bool{}
+		}
+
+		t.skipped[event.Stage] = true
+
+		return
+	case modules.StageFinished:
+		timing.Status = status(event.Err)
+		if t.skipped[event.Stage] {
+			timing.Status = statusSkipped
+		}
+	case modules.ModuleFinished, modules.ModuleFailed:
+		timing.Module = event.Module
+		timing.Status = status(event.Err)
+	case modules.ModuleSkipped:
+		timing.Module = event.Module
+		timing.Status = statusSkipped
+	default:
+		return
+	}
+
+	t.items = append(t.items, timing)
+}
+
+// list returns timings collected so far
+func (t *timings) list() []*ctx.Timing {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return append([]*ctx.Timing{}, t.items...)
+}
+
+func status(err error) string {
+	if err != nil {
+		return statusFailed
+	}
+
+	return statusDone
+}
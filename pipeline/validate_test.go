@@ -0,0 +1,114 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/julian7/goshipdone/pipeline"
+)
+
+func TestLoadBuildPipeline_validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		ymlcontent string
+		errStr     string
+	}{
+		{
+			name:       "valid",
+			ymlcontent: "---\nbuilds:\n- type: go\n  goarch: [amd64]\n",
+		},
+		{
+			name:       "unknown stage",
+			ymlcontent: "---\nbuild:\n- type: go\n",
+			errStr:     `line 2, column 1: unknown stage "build"`,
+		},
+		{
+			name:       "unknown field",
+			ymlcontent: "---\nbuilds:\n- type: go\n  goarhc: [amd64]\n",
+			errStr:     `line 4, column 3: module build:go: unknown field "goarhc"`,
+		},
+		{
+			name:       "merged unknown field",
+			ymlcontent: "---\nx: &defaults\n  goarhc: [amd64]\nbuilds:\n- type: go\n  <<: *defaults\n",
+			errStr: "line 2, column 1: unknown stage \"x\"\n" +
+				`line 3, column 3: module build:go: unknown field "goarhc"`,
+		},
+		{
+			name:       "anchor block",
+			ymlcontent: "---\nx-common: &common\n  goarch: [amd64]\nbuilds:\n- type: go\n  <<: *common\n",
+		},
+		{
+			name:       "merged unknown field from anchor block",
+			ymlcontent: "---\nx-common: &common\n  goarhc: [amd64]\nbuilds:\n- type: go\n  <<: *common\n",
+			errStr:     `line 3, column 3: module build:go: unknown field "goarhc"`,
+		},
+		{
+			name:       "unknown field in map values",
+			ymlcontent: "---\nbuilds:\n- type: go\n  cgo:\n    targets:\n      linux-*: {cflag: -O2}\n",
+			errStr:     `line 6, column 17: module build:go: cgo: targets: linux-*: unknown field "cflag"`,
+		},
+		{
+			name:       "invalid storage",
+			ymlcontent: "---\npublishes:\n- type: artifact\n  storage: bitbucket\n",
+			errStr:     "line 4, column 12: module publish:artifact: invalid storage: `bitbucket`",
+		},
+		{
+			name:       "invalid condition",
+			ymlcontent: "---\nbuilds:\n- type: go\n  if: \"{{.Git.Tag\"\n",
+			errStr:     `line 4, column 7: module build:go: if: template: if:1: unclosed action`,
+		},
+		{
+			name:       "condition is not a string",
+			ymlcontent: "---\nbuilds:\n- type: go\n  if: [a]\n",
+			errStr:     `line 4, column 7: module build:go: if: must be a string`,
+		},
+		{
+			name:       "invalid timeout",
+			ymlcontent: "---\nbuilds:\n- type: go\n  timeout: soon\n",
+			errStr:     `line 4, column 12: module build:go: timeout: time: invalid duration "soon"`,
+		},
+		{
+			name:       "negative timeout",
+			ymlcontent: "---\nbuilds:\n- type: go\n  timeout: -1s\n",
+			errStr:     `line 4, column 12: module build:go: timeout: must be positive`,
+		},
+		{
+			name: "all problems at once",
+			ymlcontent: `---
+builds:
+- type: go
+  goarm: [9]
+  id: ""
+publishes:
+- type: artifact
+  name: goshipdone
+`,
+			errStr: "line 5, column 3: module build:go: id: required\n" +
+				"line 4, column 3: module build:go: goarm: invalid ARM version 9, valid values are 5, 6, and 7\n" +
+				"line 7, column 3: module publish:artifact: owner: required\n" +
+				"line 7, column 3: module publish:artifact: release_notes: required",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.LoadBuildPipeline([]byte(tt.ymlcontent))
+			if tt.errStr == "" {
+				if err != nil {
+					t.Errorf("LoadBuildPipeline() unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Errorf("LoadBuildPipeline() unexpected success")
+				return
+			}
+
+			if err.Error() != tt.errStr {
+				t.Errorf("LoadBuildPipeline() error = %q, want %q", err, tt.errStr)
+			}
+		})
+	}
+}